@@ -0,0 +1,84 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+	"sync"
+	"time"
+)
+
+// AdaptiveBatcher runs a session in chunks whose size is tuned after every chunk so each
+// inference call takes about a target latency, trading throughput for latency as the load changes.
+type AdaptiveBatcher struct {
+	session  *TaggerSession
+	target   time.Duration
+	minBatch int
+	maxBatch int
+
+	mu   sync.Mutex
+	size int
+}
+
+// NewAdaptiveBatcher creates an AdaptiveBatcher for session that keeps the chunk size between minBatch
+// and maxBatch, starting at minBatch. For fixed batch models maxBatch can't exceed the model batch size.
+func NewAdaptiveBatcher(session *TaggerSession, target time.Duration, minBatch, maxBatch int) (*AdaptiveBatcher, error) {
+	if target <= 0 {
+		return nil, fmt.Errorf("invalid target latency %s, it must be positive", target)
+	}
+	if minBatch < 1 || maxBatch < minBatch {
+		return nil, fmt.Errorf("invalid batch bounds [%d, %d]", minBatch, maxBatch)
+	}
+	if _, err := session.chunkSize(maxBatch, maxBatch); err != nil {
+		return nil, err
+	}
+
+	return &AdaptiveBatcher{
+		session:  session,
+		target:   target,
+		minBatch: minBatch,
+		maxBatch: maxBatch,
+		size:     minBatch,
+	}, nil
+}
+
+// Run tags the images in chunks of the current batch size, adjusting it after each chunk,
+// opts.MaxBatch is ignored
+func (b *AdaptiveBatcher) Run(images []image.Image, opts RunOptions) ([]Predictions, error) {
+	predictions := make([]Predictions, 0, len(images))
+	for start := 0; start < len(images); {
+		end := min(start+b.BatchSize(), len(images))
+
+		chunkOpts := opts
+		chunkOpts.MaxBatch = end - start
+
+		began := time.Now()
+		out, err := b.session.RunWithOptions(images[start:end], chunkOpts)
+		if err != nil {
+			return nil, fmt.Errorf("images %d to %d: %w", start, end-1, err)
+		}
+		b.adjust(end-start, time.Since(began))
+
+		predictions = append(predictions, out...)
+		start = end
+	}
+
+	return predictions, nil
+}
+
+// adjust scales the batch size by how far the last chunk of n images was from the target latency
+func (b *AdaptiveBatcher) adjust(n int, took time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size := int(float64(n) * float64(b.target) / float64(max(took, 1)))
+	// grow at most twice per chunk so a single fast chunk doesn't overshoot
+	b.size = min(max(size, b.minBatch), b.maxBatch, 2*b.size)
+}
+
+// BatchSize returns the batch size used by the next chunk
+func (b *AdaptiveBatcher) BatchSize() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
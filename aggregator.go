@@ -0,0 +1,102 @@
+package gotagger
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TagCount is how often a tag appears in the predictions added to an Aggregator
+type TagCount struct {
+	Name     string   `json:"name"`
+	Category Category `json:"category"`
+	// Count is the amount of predictions with the tag
+	Count int `json:"count"`
+	// AverageScore is the mean score of the tag over the predictions with it
+	AverageScore float32 `json:"average_score"`
+}
+
+// tagKey identifies a tag in an Aggregator, a name can be in more than one category
+type tagKey struct {
+	name     string
+	category Category
+}
+
+// tagTally is the running count and score sum of a tag
+type tagTally struct {
+	count int
+	sum   float64
+}
+
+// Aggregator tallies the general and character tags of many predictions to build dataset tag frequency reports.
+// It is safe to use from multiple goroutines and the zero value is ready to use.
+type Aggregator struct {
+	mu     sync.Mutex
+	images int
+	tags   map[tagKey]*tagTally
+}
+
+// Add counts the general and character tags of p
+func (a *Aggregator) Add(p Predictions) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.tags == nil {
+		a.tags = make(map[tagKey]*tagTally)
+	}
+
+	a.images++
+	for _, category := range []Category{CategoryGeneral, CategoryCharacter} {
+		for name, score := range p.scores(category) {
+			key := tagKey{name, category}
+			tally, ok := a.tags[key]
+			if !ok {
+				tally = &tagTally{}
+				a.tags[key] = tally
+			}
+			tally.count++
+			tally.sum += float64(score)
+		}
+	}
+}
+
+// Images returns the amount of predictions added
+func (a *Aggregator) Images() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.images
+}
+
+// TopTags returns the n most frequent tags, or every tag if n is not positive, sorted by descending count.
+// Tags with the same count are sorted by descending average score then by name
+func (a *Aggregator) TopTags(n int) []TagCount {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	counts := make([]TagCount, 0, len(a.tags))
+	for key, tally := range a.tags {
+		counts = append(counts, TagCount{
+			Name:         key.name,
+			Category:     key.category,
+			Count:        tally.count,
+			AverageScore: float32(tally.sum / float64(tally.count)),
+		})
+	}
+
+	slices.SortFunc(counts, func(x, y TagCount) int {
+		return cmp.Or(
+			cmp.Compare(y.Count, x.Count),
+			cmp.Compare(y.AverageScore, x.AverageScore),
+			strings.Compare(x.Name, y.Name),
+			cmp.Compare(x.Category, y.Category),
+		)
+	})
+
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+
+	return counts
+}
@@ -0,0 +1,123 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"slices"
+	"time"
+)
+
+// benchmarkWarmup is the amount of iterations Benchmark runs before measuring
+const benchmarkWarmup = 2
+
+// BenchStats are the timings of the iterations of Benchmark, each one being an inference of the whole batch
+type BenchStats struct {
+	// Iterations is the amount of measured iterations, without the warmup ones
+	Iterations int
+	// Images is the amount of images of every iteration
+	Images int
+	Min    time.Duration
+	Median time.Duration
+	P99    time.Duration
+	Mean   time.Duration
+	Max    time.Duration
+}
+
+// Benchmark runs the images through the session iterations times and returns the timings, to measure
+// the effect of the provider or the batch size of opts.
+//
+// The images are preprocessed once and the tensors are reused, so only the inference is measured.
+// 2 warmup iterations run first and are not part of the stats.
+func (s *TaggerSession) Benchmark(images []image.Image, iterations int, opts RunOptions) (BenchStats, error) {
+	if iterations < 1 {
+		return BenchStats{}, errors.New("iterations must be at least 1")
+	}
+	if len(images) == 0 {
+		return BenchStats{}, errors.New("benchmark needs at least one image")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if *s.destroyed {
+		return BenchStats{}, ErrSessionDestroyed
+	}
+
+	batch, err := s.chunkSize(len(images), opts.MaxBatch)
+	if err != nil {
+		return BenchStats{}, err
+	}
+
+	type chunk struct {
+		tensors *chunkTensors
+		data    []float32
+	}
+	var chunks []chunk
+	defer func() {
+		// chunks with the same shape share their tensors
+		for i, c := range chunks {
+			if i == 0 || c.tensors != chunks[i-1].tensors {
+				c.tensors.destroy()
+			}
+		}
+	}()
+
+	imgSize := 3 * s.targetSize * s.targetSize
+	for start := 0; start < len(images); start += batch {
+		end := min(start+batch, len(images))
+		rows := end - start
+		if s.batchSize != -1 {
+			rows = s.batchSize
+		}
+
+		data, err := prepareChunk(start, end, rows*imgSize, imgSize, func(i int) ([]float32, error) {
+			return s.prepareImage(images[i], opts)
+		})
+		if err != nil {
+			return BenchStats{}, err
+		}
+
+		inShape := s.chunkShape(rows)
+		if len(chunks) != 0 && slices.Equal(chunks[len(chunks)-1].tensors.inShape, inShape) {
+			chunks = append(chunks, chunk{chunks[len(chunks)-1].tensors, data})
+			continue
+		}
+
+		tensors, err := s.newChunkTensors(inShape, rows)
+		if err != nil {
+			return BenchStats{}, chunkError(err, len(chunks), start, end)
+		}
+		chunks = append(chunks, chunk{tensors, data})
+	}
+
+	timings := make([]time.Duration, 0, iterations)
+	for iteration := range benchmarkWarmup + iterations {
+		began := time.Now()
+		for i, c := range chunks {
+			if _, _, err := s.infer(c.tensors, c.data, 0); err != nil {
+				return BenchStats{}, chunkError(err, i, i*batch, min((i+1)*batch, len(images)))
+			}
+		}
+
+		if iteration >= benchmarkWarmup {
+			timings = append(timings, time.Since(began))
+		}
+	}
+
+	slices.Sort(timings)
+
+	var total time.Duration
+	for _, timing := range timings {
+		total += timing
+	}
+
+	return BenchStats{
+		Iterations: iterations,
+		Images:     len(images),
+		Min:        timings[0],
+		Median:     timings[len(timings)/2],
+		P99:        timings[(len(timings)*99+99)/100-1],
+		Mean:       total / time.Duration(len(timings)),
+		Max:        timings[len(timings)-1],
+	}, nil
+}
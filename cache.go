@@ -0,0 +1,233 @@
+package gotagger
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// Cache stores predictions keyed by image content, implementations must be safe for concurrent use
+type Cache interface {
+	Get(key string) (Predictions, bool)
+	Add(key string, p Predictions)
+}
+
+// LRUCache is a Cache that keeps up to a fixed amount of predictions, evicting the least recently used
+type LRUCache struct {
+	lru[Predictions]
+}
+
+// NewLRUCache creates a LRUCache that holds up to size predictions
+func NewLRUCache(size int) *LRUCache {
+	c := &LRUCache{}
+	c.init(size)
+	return c
+}
+
+// PreprocessCache keeps up to a fixed amount of preprocessed images, evicting the least recently used,
+// see RunOptions.PreprocessCache
+type PreprocessCache struct {
+	lru[[]float32]
+}
+
+// NewPreprocessCache creates a PreprocessCache that holds up to size preprocessed images.
+//
+// Each entry takes 12 bytes per pixel of the model input, about 2.4MB for 448x448 models.
+func NewPreprocessCache(size int) *PreprocessCache {
+	c := &PreprocessCache{}
+	c.init(size)
+	return c
+}
+
+// lru is a least recently used cache safe for concurrent use
+type lru[V any] struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func (c *lru[V]) init(size int) {
+	c.size = max(size, 1)
+	c.entries = map[string]*list.Element{}
+	c.order = list.New()
+}
+
+// Get returns the value stored for key
+func (c *lru[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		var zero V
+		return zero, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry[V]).value, true
+}
+
+// Add stores the value for key, evicting the least recently used entry if the cache is full
+func (c *lru[V]) Add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry[V]).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key, value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[V]).key)
+	}
+}
+
+// Len returns the amount of stored values
+func (c *lru[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Hits returns how many Get calls found the key
+func (c *lru[V]) Hits() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.hits
+}
+
+// Misses returns how many Get calls did not find the key
+func (c *lru[V]) Misses() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.misses
+}
+
+// ImageHash returns the hex SHA-256 of the size and pixels of img, it is the key used with Cache
+func ImageHash(img image.Image) string {
+	h := sha256.New()
+	bounds := img.Bounds()
+
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf, uint32(bounds.Dx()))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(bounds.Dy()))
+	h.Write(buf)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			binary.LittleEndian.PutUint16(buf, uint16(r))
+			binary.LittleEndian.PutUint16(buf[2:], uint16(g))
+			binary.LittleEndian.PutUint16(buf[4:], uint16(b))
+			binary.LittleEndian.PutUint16(buf[6:], uint16(a))
+			h.Write(buf)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// preprocessKey is the PreprocessCache key of img, it includes every setting used by prepareInput
+func (s *TaggerSession) preprocessKey(img image.Image, opts RunOptions) string {
+	var padColor string
+	if opts.PadColor != nil {
+		r, g, b, a := opts.PadColor.RGBA()
+		padColor = fmt.Sprintf("%04x%04x%04x%04x", r, g, b, a)
+	}
+
+	return fmt.Sprintf(
+		"%s/%d/%s/%d/%d/%s/%d/%d",
+		ImageHash(img),
+		s.targetSize,
+		s.channelOrder,
+		opts.Preprocess,
+		opts.Padding,
+		padColor,
+		opts.MaxInputDimension,
+		opts.Resample,
+	)
+}
+
+// runCached runs only the images missing from opts.Cache and stores their predictions, see runPredictions
+func (s *TaggerSession) runCached(images []image.Image, opts RunOptions) ([]Predictions, []error, error) {
+	predictions := make([]Predictions, len(images))
+	keys := make([]string, len(images))
+
+	var (
+		missing []image.Image
+		indexes []int
+	)
+	for i, img := range images {
+		keys[i] = ImageHash(img)
+		if p, ok := opts.Cache.Get(keys[i]); ok {
+			predictions[i] = p
+			continue
+		}
+
+		missing = append(missing, img)
+		indexes = append(indexes, i)
+	}
+
+	if len(missing) == 0 {
+		return predictions, make([]error, len(images)), nil
+	}
+
+	out, outErrs, err := s.runPredictions(missing, opts)
+	if out == nil {
+		return nil, nil, err
+	}
+
+	predictions, errs := fillCached(predictions, indexes, keys, out, outErrs, opts.Cache)
+	return predictions, errs, err
+}
+
+// fillCached puts the predictions out of the images at indexes into predictions and adds them to cache,
+// the images failed by outErrs get their error instead.
+// out may be short when a chunk timed out, then only the results before the first image missing from it
+// are returned, like without a cache
+func fillCached(
+	predictions []Predictions,
+	indexes []int,
+	keys []string,
+	out []Predictions,
+	outErrs []error,
+	cache Cache,
+) ([]Predictions, []error) {
+	errs := make([]error, len(predictions))
+	for j, i := range indexes[:len(out)] {
+		if outErrs[j] != nil {
+			errs[i] = withIndex(outErrs[j], i)
+			continue
+		}
+
+		predictions[i] = out[j]
+		cache.Add(keys[i], out[j])
+	}
+
+	if len(out) < len(indexes) {
+		return predictions[:indexes[len(out)]], errs[:indexes[len(out)]]
+	}
+
+	return predictions, errs
+}
@@ -0,0 +1,51 @@
+package gotagger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFillCachedPartial(t *testing.T) {
+	cache := NewLRUCache(8)
+	keys := []string{"a", "b", "c", "d", "e"}
+
+	// a and c were cached, b, d and e ran but the chunk of d and e timed out
+	predictions := make([]Predictions, len(keys))
+	predictions[0] = Predictions{GeneralThresholdUsed: 0.1}
+	predictions[2] = Predictions{GeneralThresholdUsed: 0.3}
+	out := []Predictions{{GeneralThresholdUsed: 0.2}}
+
+	got, errs := fillCached(predictions, []int{1, 3, 4}, keys, out, make([]error, 1), cache)
+
+	if len(got) != 3 || len(errs) != 3 {
+		t.Fatalf("got %d predictions and %d errors, want the 3 before the timed out image", len(got), len(errs))
+	}
+	for i, want := range []float32{0.1, 0.2, 0.3} {
+		if got[i].GeneralThresholdUsed != want {
+			t.Errorf("prediction %d: got threshold %v, want %v", i, got[i].GeneralThresholdUsed, want)
+		}
+	}
+	if _, ok := cache.Get("b"); !ok {
+		t.Error("the completed prediction was not cached")
+	}
+	if _, ok := cache.Get("d"); ok {
+		t.Error("the timed out prediction was cached")
+	}
+
+	complete, errs := fillCached(make([]Predictions, 2), []int{0, 1}, keys, make([]Predictions, 2), make([]error, 2), cache)
+	if len(complete) != 2 || len(errs) != 2 {
+		t.Errorf("complete run: got %d predictions and %d errors, want 2", len(complete), len(errs))
+	}
+
+	// a non finite output fails only its image, which is not cached
+	nonFinite := &ImageError{Index: 1, Err: ErrNonFinite}
+	_, errs = fillCached(make([]Predictions, 3), []int{0, 2}, []string{"x", "y", "z"}, make([]Predictions, 2),
+		[]error{nil, nonFinite}, cache)
+	var imageErr *ImageError
+	if errs[0] != nil || !errors.As(errs[2], &imageErr) || imageErr.Index != 2 || !errors.Is(errs[2], ErrNonFinite) {
+		t.Errorf("non finite output: got %v, want an ImageError of image 2", errs)
+	}
+	if _, ok := cache.Get("z"); ok {
+		t.Error("the non finite prediction was cached")
+	}
+}
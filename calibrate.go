@@ -0,0 +1,91 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+)
+
+// calibrationSteps is the amount of thresholds tried by CalibrateThreshold, from 0.01 to 0.99
+const calibrationSteps = 99
+
+// Calibration is the result of CalibrateThreshold
+type Calibration struct {
+	Threshold float32
+	Precision float32
+	Recall    float32
+	F1        float32
+}
+
+// CalibrateThreshold finds the threshold of category that maximizes the F1 score against labeled images.
+//
+// truth[i] holds the tags that are present in images[i], keyed by their name as found in Predictions,
+// tags that are missing or false are considered absent. Precision and recall are computed over all
+// the tags of all images, only the batching settings, ApplySigmoid and Temperature of opts are used.
+func (s *TaggerSession) CalibrateThreshold(
+	images []image.Image,
+	truth []map[string]bool,
+	category Category,
+	opts RunOptions,
+) (Calibration, error) {
+	if len(images) != len(truth) {
+		return Calibration{}, fmt.Errorf("got %d images but %d truth sets", len(images), len(truth))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	indexes := s.indexes(category)
+	if len(indexes) == 0 {
+		return Calibration{}, fmt.Errorf("the tags dataset has no %s tags", category)
+	}
+
+	raw, err := s.runRaw(images, opts)
+	if err != nil {
+		return Calibration{}, err
+	}
+	for i, data := range raw {
+		raw[i] = scaleScores(data, opts)
+	}
+
+	var best Calibration
+	for step := 1; step <= calibrationSteps; step++ {
+		threshold := float32(step) / (calibrationSteps + 1)
+
+		var tp, fp, fn int
+		for i, data := range raw {
+			for _, index := range indexes {
+				if index >= len(data) {
+					continue
+				}
+
+				predicted := data[index] > threshold
+				actual := truth[i][s.names[index]]
+				switch {
+				case predicted && actual:
+					tp++
+				case predicted:
+					fp++
+				case actual:
+					fn++
+				}
+			}
+		}
+
+		c := Calibration{Threshold: threshold}
+		if tp+fp > 0 {
+			c.Precision = float32(tp) / float32(tp+fp)
+		}
+		if tp+fn > 0 {
+			c.Recall = float32(tp) / float32(tp+fn)
+		}
+		if c.Precision+c.Recall > 0 {
+			c.F1 = 2 * c.Precision * c.Recall / (c.Precision + c.Recall)
+		}
+
+		if step == 1 || c.F1 > best.F1 {
+			best = c
+		}
+	}
+
+	return best, nil
+}
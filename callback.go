@@ -0,0 +1,36 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+	"slices"
+)
+
+// RunCallback tags the images in batches and calls fn with the predictions of every image, in input order,
+// as soon as its batch is done, so the predictions of a huge batch don't need to be held at once.
+//
+// It stops at the first error returned by fn and returns it. Like RunJSONL, it uses batches of
+// RunOptions.MaxBatch images, or 32 for dynamic batch models without it.
+func (s *TaggerSession) RunCallback(images []image.Image, opts RunOptions, fn func(i int, p Predictions) error) error {
+	batch, err := s.chunkSize(streamBatchSize, opts.MaxBatch)
+	if err != nil {
+		return err
+	}
+
+	start := 0
+	for chunk := range slices.Chunk(images, batch) {
+		predictions, err := s.RunWithOptions(chunk, opts)
+		if err != nil {
+			return fmt.Errorf("images %d to %d: %w", start, start+len(chunk)-1, err)
+		}
+
+		for i, p := range predictions {
+			if err := fn(start+i, p); err != nil {
+				return err
+			}
+		}
+		start += len(chunk)
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrCaptionExists is returned when a caption file already exists and overwriting is disabled
+var ErrCaptionExists = errors.New("caption file already exists")
+
+// CaptionOptions are the settings used when writing caption files
+type CaptionOptions struct {
+	// Separator between tags, defaults to ", "
+	Separator string
+	// IncludeCharacter writes the character tags before the general tags
+	IncludeCharacter bool
+	// IncludeRating writes the most likely rating after the general tags
+	IncludeRating bool
+	// Overwrite replaces existing caption files instead of returning ErrCaptionExists
+	Overwrite bool
+}
+
+// Caption builds the caption text for the predictions.
+//
+// Character tags (if included) go first, then the general tags, then the best rating (if included),
+// each sorted by descending score.
+func (p *Predictions) Caption(opts CaptionOptions) string {
+	sep := opts.Separator
+	if sep == "" {
+		sep = ", "
+	}
+
+	var tags []string
+	if opts.IncludeCharacter {
+		tags = append(tags, sortedKeys(p.Character)...)
+	}
+
+	tags = append(tags, p.Names()...)
+
+	if opts.IncludeRating && len(p.Rating) != 0 {
+		tags = append(tags, sortedKeys(p.Rating)[0])
+	}
+
+	return strings.Join(tags, sep)
+}
+
+// DanbooruString returns the general tags, and the character ones if includeCharacter is set,
+// as a Danbooru tag string: underscored names joined by spaces, sorted by descending score.
+//
+// Names come from RawNames when set, otherwise spaces are replaced with underscores.
+func (p *Predictions) DanbooruString(includeCharacter bool) string {
+	scores := maps.Clone(p.General)
+	if includeCharacter {
+		maps.Copy(scores, p.Character)
+	}
+
+	tags := sortedKeys(scores)
+	for i, name := range tags {
+		if raw, ok := p.RawNames[name]; ok {
+			tags[i] = raw
+		} else {
+			tags[i] = strings.ReplaceAll(name, " ", "_")
+		}
+	}
+
+	return strings.Join(tags, " ")
+}
+
+// WriteCaptionFile writes the caption of the predictions into path
+func (p *Predictions) WriteCaptionFile(path string, opts CaptionOptions) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !opts.Overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s: %w", path, ErrCaptionExists)
+		}
+		return fmt.Errorf("error while trying to open file %s: %w", path, err)
+	}
+
+	if _, err := file.WriteString(p.Caption(opts)); err != nil {
+		file.Close()
+		return fmt.Errorf("error while writing caption file %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+// SidecarPath returns the caption path for an image, which is the same path with a .txt extension
+func SidecarPath(imagePath string) string {
+	return strings.TrimSuffix(imagePath, filepath.Ext(imagePath)) + ".txt"
+}
+
+// RunPathsToSidecars tags every image in paths and writes the caption next to it (see SidecarPath).
+//
+// The returned slice has the same length as paths, errs[i] is non-nil when image i
+// could not be decoded, tagged or its caption could not be written. Like with RunEach, an image that fails
+// only fails itself and the captions of the others are still written.
+func (s *TaggerSession) RunPathsToSidecars(paths []string, opts RunOptions, captionOpts CaptionOptions) []error {
+	errs := make([]error, len(paths))
+
+	images := make([]image.Image, 0, len(paths))
+	indexes := make([]int, 0, len(paths))
+	for i, path := range paths {
+		if !captionOpts.Overwrite {
+			if _, err := os.Stat(SidecarPath(path)); err == nil {
+				errs[i] = fmt.Errorf("%s: %w", SidecarPath(path), ErrCaptionExists)
+				continue
+			}
+		}
+
+		img, err := decodeFile(path)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		images = append(images, img)
+		indexes = append(indexes, i)
+	}
+
+	if len(images) == 0 {
+		return errs
+	}
+
+	predictions, imageErrs := s.RunEach(images, opts)
+	for j, i := range indexes {
+		if imageErrs[j] != nil {
+			errs[i] = fmt.Errorf("%s: %w", paths[i], imageCause(imageErrs[j]))
+			continue
+		}
+		errs[i] = predictions[j].WriteCaptionFile(SidecarPath(paths[i]), captionOpts)
+	}
+
+	return errs
+}
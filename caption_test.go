@@ -0,0 +1,57 @@
+package gotagger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunPathsToSidecarsPerImageErrors(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.png")
+	large := filepath.Join(dir, "large.png")
+	writePNG(t, small, 32)
+	writePNG(t, large, 96)
+
+	s := destroyedSession()
+	errs := s.RunPathsToSidecars([]string{small, large}, RunOptions{MinResolution: 64}, CaptionOptions{})
+
+	if !errors.Is(errs[0], ErrLowResolution) {
+		t.Errorf("small: got %v, want ErrLowResolution", errs[0])
+	}
+	if !errors.Is(errs[1], ErrSessionDestroyed) {
+		t.Errorf("large: got %v, want the run error", errs[1])
+	}
+	if _, err := os.Stat(SidecarPath(small)); err == nil {
+		t.Error("a caption was written for the rejected image")
+	}
+}
+
+func TestRunPathsToSidecarsWritesValidImages(t *testing.T) {
+	model, tags := testModel(t)
+
+	s, err := New(model, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Destroy()
+
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.png")
+	large := filepath.Join(dir, "large.png")
+	writePNG(t, small, 32)
+	writePNG(t, large, 96)
+
+	errs := s.RunPathsToSidecars([]string{small, large}, RunOptions{MinResolution: 64}, CaptionOptions{})
+
+	if !errors.Is(errs[0], ErrLowResolution) {
+		t.Errorf("small: got %v, want ErrLowResolution", errs[0])
+	}
+	if errs[1] != nil {
+		t.Fatalf("large: got %v, want nil", errs[1])
+	}
+	if _, err := os.Stat(SidecarPath(large)); err != nil {
+		t.Errorf("the caption of the valid image was not written: %v", err)
+	}
+}
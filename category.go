@@ -0,0 +1,130 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Category is the category of a tag
+type Category int
+
+const (
+	// CategoryGeneral are the descriptive tags
+	CategoryGeneral Category = iota
+	// CategoryCharacter are the character tags
+	CategoryCharacter
+	// CategoryRating are the rating labels
+	CategoryRating
+	// CategoryArtist are the artist tags
+	CategoryArtist
+	// CategoryCopyright are the copyright (series) tags
+	CategoryCopyright
+	// CategoryMeta are the meta tags, like the image format or its quality
+	CategoryMeta
+)
+
+// categoryCodes maps the codes of the category column of the tags dataset to their Category,
+// they are the Danbooru codes plus 9 for the ratings
+var categoryCodes = map[string]Category{
+	"0": CategoryGeneral,
+	"1": CategoryArtist,
+	"3": CategoryCopyright,
+	"4": CategoryCharacter,
+	"5": CategoryMeta,
+	"9": CategoryRating,
+}
+
+func (c Category) String() string {
+	switch c {
+	case CategoryGeneral:
+		return "general"
+	case CategoryCharacter:
+		return "character"
+	case CategoryRating:
+		return "rating"
+	case CategoryArtist:
+		return "artist"
+	case CategoryCopyright:
+		return "copyright"
+	case CategoryMeta:
+		return "meta"
+	}
+	return fmt.Sprintf("Category(%d)", int(c))
+}
+
+// indexes returns the tag indexes of the category, nil if the category is unknown
+func (t *modelTags) indexes(c Category) []int {
+	switch c {
+	case CategoryGeneral:
+		return t.generalIndexes
+	case CategoryCharacter:
+		return t.characterIndexes
+	case CategoryRating:
+		return t.ratingIndexes
+	case CategoryArtist:
+		return t.artistIndexes
+	case CategoryCopyright:
+		return t.copyrightIndexes
+	case CategoryMeta:
+		return t.metaIndexes
+	}
+	return nil
+}
+
+// ErrMissingCategory is returned by NewWithOptions and ReloadTags with Options.StrictCategories
+// when the tags dataset has no general, character or rating tags
+var ErrMissingCategory = errors.New("tags dataset has no tags of a category")
+
+// checkCategories returns an error matching ErrMissingCategory with the tags of every category
+// when the general, character or rating tags are missing
+func (t *modelTags) checkCategories() error {
+	var missing []Category
+	for _, c := range []Category{CategoryGeneral, CategoryCharacter, CategoryRating} {
+		if len(t.indexes(c)) == 0 {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w: missing %v, found %d general, %d character and %d rating tags",
+		ErrMissingCategory,
+		missing,
+		len(t.generalIndexes),
+		len(t.characterIndexes),
+		len(t.ratingIndexes),
+	)
+}
+
+// checkTagCategories checks the categories of freshly loaded tags, returning the error of checkCategories
+// when strict or logging it otherwise, and logs unexpected rating names
+func checkTagCategories(tags *modelTags, strict bool, logger *slog.Logger) error {
+	if missing := tags.checkCategories(); missing != nil {
+		if strict {
+			return missing
+		}
+		logger.Warn("the tags dataset looks malformed or mismatched", "error", missing)
+	}
+
+	if unknown := tags.unknownRatings(); len(unknown) != 0 {
+		logger.Warn("the tags dataset has unexpected rating names, its categories may be shifted", "ratings", unknown)
+	}
+
+	return nil
+}
+
+// scores returns the tags of the category in the predictions, nil if the category is unknown
+func (p *Predictions) scores(c Category) map[string]float32 {
+	switch c {
+	case CategoryGeneral:
+		return p.General
+	case CategoryCharacter:
+		return p.Character
+	case CategoryRating:
+		return p.Rating
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// ChannelOrder is the order of the color channels of the model input
+type ChannelOrder int
+
+const (
+	// ChannelOrderAuto detects the order from the model, see NewWithOptions
+	ChannelOrderAuto ChannelOrder = iota
+	// ChannelOrderBGR is the order of the WD models
+	ChannelOrderBGR
+	// ChannelOrderRGB is the order of most models trained outside of the WD family
+	ChannelOrderRGB
+)
+
+func (c ChannelOrder) String() string {
+	switch c {
+	case ChannelOrderAuto:
+		return "auto"
+	case ChannelOrderBGR:
+		return "BGR"
+	case ChannelOrderRGB:
+		return "RGB"
+	}
+	return fmt.Sprintf("ChannelOrder(%d)", int(c))
+}
+
+// MarshalText encodes the order as auto, BGR or RGB
+func (c ChannelOrder) MarshalText() ([]byte, error) {
+	switch c {
+	case ChannelOrderAuto, ChannelOrderBGR, ChannelOrderRGB:
+		return []byte(c.String()), nil
+	}
+	return nil, fmt.Errorf("unknown channel order %d", int(c))
+}
+
+// UnmarshalText decodes an order encoded by MarshalText, in any case
+func (c *ChannelOrder) UnmarshalText(text []byte) error {
+	for _, order := range []ChannelOrder{ChannelOrderAuto, ChannelOrderBGR, ChannelOrderRGB} {
+		if strings.EqualFold(string(text), order.String()) {
+			*c = order
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown channel order %q, expected auto, BGR or RGB", text)
+}
+
+// channelOrderKeys are the custom metadata keys checked for the channel order
+var channelOrderKeys = []string{"channel_order", "color_order", "color_format", "channels"}
+
+// detectChannelOrder guesses the channel order of a model.
+//
+// The values of channelOrderKeys in the metadata are checked first, then the input name,
+// looking for "rgb" or "bgr" in any case. Models hinting neither are assumed to be BGR like the WD models,
+// so RGB models that don't say so need Options.ChannelOrder.
+func detectChannelOrder(inputName string, metadata map[string]string) ChannelOrder {
+	hints := make([]string, 0, len(channelOrderKeys)+1)
+	for _, key := range channelOrderKeys {
+		if v, ok := metadata[key]; ok {
+			hints = append(hints, v)
+		}
+	}
+	hints = append(hints, inputName)
+
+	for _, hint := range hints {
+		hint = strings.ToLower(hint)
+		switch {
+		case strings.Contains(hint, "bgr"):
+			return ChannelOrderBGR
+		case strings.Contains(hint, "rgb"):
+			return ChannelOrderRGB
+		}
+	}
+
+	return ChannelOrderBGR
+}
+
+// ChannelOrder returns the channel order the images are fed to the model with
+func (s *TaggerSession) ChannelOrder() ChannelOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.channelOrder
+}
+
+// DiagnoseChannelOrder runs img with both channel orders and recommends the one whose best general tag
+// is the most confident, a model fed with the wrong order tends to be unsure about everything.
+//
+// It is a heuristic meant to check Options.ChannelOrder on a few typical images, not to be run on every image.
+func (s *TaggerSession) DiagnoseChannelOrder(img image.Image) (
+	recommended ChannelOrder,
+	bgrScore float32,
+	rgbScore float32,
+	err error,
+) {
+	if err := validateImage(img, RunOptions{}); err != nil {
+		return ChannelOrderAuto, 0, 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := []ChannelOrder{ChannelOrderBGR, ChannelOrderRGB}
+	raw, err := s.runBatches(len(orders), RunOptions{}, func(i int) ([]float32, error) {
+		return prepareInput(img, s.targetSize, orders[i], RunOptions{}), nil
+	})
+	if err != nil {
+		return ChannelOrderAuto, 0, 0, err
+	}
+
+	scores := make([]float32, len(orders))
+	for i, data := range raw {
+		for _, index := range s.generalIndexes {
+			if index < len(data) {
+				scores[i] = max(scores[i], data[index])
+			}
+		}
+	}
+
+	recommended = ChannelOrderBGR
+	if scores[1] > scores[0] {
+		recommended = ChannelOrderRGB
+	}
+
+	return recommended, scores[0], scores[1], nil
+}
@@ -0,0 +1,51 @@
+package gotagger
+
+import "fmt"
+
+// SessionConfig is the configuration of a session that doesn't come from the model,
+// it can be encoded to JSON to version it alongside the results and restored with ApplyConfig.
+//
+// RunOptions.PadColor, Cache, PreprocessCache and HTTPClient are not encoded.
+type SessionConfig struct {
+	// ModelPath and TagsPath are the files the session was created from, only for reference.
+	// The tags datasets of NewWithTagFiles are joined with the OS path list separator
+	ModelPath string `json:"model_path"`
+	TagsPath  string `json:"tags_path"`
+	// ChannelOrder is the channel order of the model input, never ChannelOrderAuto when returned by Config
+	ChannelOrder ChannelOrder `json:"channel_order"`
+	// Defaults are the options used by RunDefault
+	Defaults RunOptions `json:"defaults"`
+}
+
+// Config returns the current configuration of the session
+func (s *TaggerSession) Config() SessionConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return SessionConfig{
+		ModelPath:    s.modelPath,
+		TagsPath:     s.tagsPath,
+		ChannelOrder: s.channelOrder,
+		Defaults:     s.defaults,
+	}
+}
+
+// ApplyConfig sets the channel order and the default options of cfg on the session,
+// ChannelOrderAuto keeps the current order. The paths are ignored, the model and tags are not reloaded
+func (s *TaggerSession) ApplyConfig(cfg SessionConfig) error {
+	switch cfg.ChannelOrder {
+	case ChannelOrderAuto, ChannelOrderBGR, ChannelOrderRGB:
+	default:
+		return fmt.Errorf("unknown channel order %s", cfg.ChannelOrder)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cfg.ChannelOrder != ChannelOrderAuto {
+		s.channelOrder = cfg.ChannelOrder
+	}
+	s.defaults = cfg.Defaults
+
+	return nil
+}
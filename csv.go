@@ -0,0 +1,73 @@
+package gotagger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// WritePredictionsCSV writes the predictions of many images to w as CSV, the rows are sorted by image path.
+//
+// The wide format has one row per image with the columns path, general, character and rating,
+// each category cell holds its tags sorted by descending score as "tag:score" pairs separated by "; ".
+// The long format (longFormat) has one row per tag with the columns image, tag, category and score,
+// the tags of an image ordered like Flatten.
+func WritePredictionsCSV(w io.Writer, results map[string]Predictions, longFormat bool) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"path", "general", "character", "rating"}
+	if longFormat {
+		header = []string{"image", "tag", "category", "score"}
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("error while writing csv header: %w", err)
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(results)) {
+		predictions := results[path]
+
+		var rows [][]string
+		if longFormat {
+			for _, tag := range predictions.Flatten() {
+				rows = append(rows, []string{path, tag.Name, tag.Category.String(), formatScore(tag.Score)})
+			}
+		} else {
+			rows = [][]string{{
+				path,
+				csvScores(predictions.General),
+				csvScores(predictions.Character),
+				csvScores(predictions.Rating),
+			}}
+		}
+
+		if err := cw.WriteAll(rows); err != nil {
+			return fmt.Errorf("error while writing csv rows of %s: %w", path, err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error while writing csv: %w", err)
+	}
+
+	return nil
+}
+
+// csvScores formats the tags of scores as "tag:score" pairs sorted by descending score
+func csvScores(scores map[string]float32) string {
+	pairs := make([]string, 0, len(scores))
+	for _, name := range sortedKeys(scores) {
+		pairs = append(pairs, name+":"+formatScore(scores[name]))
+	}
+
+	return strings.Join(pairs, "; ")
+}
+
+// formatScore formats a score with the shortest representation that round trips to the same float32
+func formatScore(score float32) string {
+	return strconv.FormatFloat(float64(score), 'f', -1, 32)
+}
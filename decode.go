@@ -0,0 +1,81 @@
+package gotagger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+)
+
+// DecodeBytes decodes an image from raw bytes using the registered image formats.
+//
+// By default only jpeg and png are registered, import other decoders (like golang.org/x/image/webp)
+// to support more formats.
+func DecodeBytes(b []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, fmt.Errorf("unsupported image format: %w", err)
+		}
+		return nil, fmt.Errorf("error while decoding image: %w", err)
+	}
+
+	return img, nil
+}
+
+// RunBytes decodes every item with DecodeBytes and runs the session on the ones that decoded.
+//
+// Both returned slices have the same length as data, errs[i] is non-nil when item i
+// could not be decoded or tagged, in which case predictions[i] is empty. Like with RunEach,
+// an item that fails only fails itself.
+func (s *TaggerSession) RunBytes(data [][]byte, opts RunOptions) ([]Predictions, []error) {
+	predictions := make([]Predictions, len(data))
+	errs := make([]error, len(data))
+
+	images := make([]image.Image, 0, len(data))
+	indexes := make([]int, 0, len(data))
+	for i, b := range data {
+		img, err := DecodeBytes(b)
+		if err != nil {
+			errs[i] = fmt.Errorf("item %d: %w", i, err)
+			continue
+		}
+
+		images = append(images, img)
+		indexes = append(indexes, i)
+	}
+
+	if len(images) == 0 {
+		return predictions, errs
+	}
+
+	out, imageErrs := s.RunEach(images, opts)
+	for j, i := range indexes {
+		if imageErrs[j] != nil {
+			errs[i] = fmt.Errorf("item %d: %w", i, imageCause(imageErrs[j]))
+			continue
+		}
+		predictions[i] = out[j]
+	}
+
+	return predictions, errs
+}
+
+func decodeFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while trying to open file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, fmt.Errorf("unsupported image format %s: %w", path, err)
+		}
+		return nil, fmt.Errorf("error while decoding image %s: %w", path, err)
+	}
+
+	return img, nil
+}
@@ -0,0 +1,32 @@
+package gotagger
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestRunBytesPerItemErrors(t *testing.T) {
+	encode := func(size int) []byte {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, size, size))); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	s := destroyedSession()
+	_, errs := s.RunBytes([][]byte{encode(96), []byte("not an image"), encode(32)}, RunOptions{MinResolution: 64})
+
+	if !errors.Is(errs[0], ErrSessionDestroyed) {
+		t.Errorf("item 0: got %v, want the run error", errs[0])
+	}
+	if !errors.Is(errs[1], image.ErrFormat) {
+		t.Errorf("item 1: got %v, want image.ErrFormat", errs[1])
+	}
+	if !errors.Is(errs[2], ErrLowResolution) {
+		t.Errorf("item 2: got %v, want ErrLowResolution", errs[2])
+	}
+}
@@ -0,0 +1,49 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"slices"
+)
+
+// dedupeImages returns the distinct images by ImageHash and, for every image, the index of its copy in unique
+func dedupeImages(images []image.Image) (unique []image.Image, positions []int) {
+	seen := map[string]int{}
+	positions = make([]int, len(images))
+	for i, img := range images {
+		key := ImageHash(img)
+		j, ok := seen[key]
+		if !ok {
+			j = len(unique)
+			seen[key] = j
+			unique = append(unique, img)
+		}
+		positions[i] = j
+	}
+
+	return unique, positions
+}
+
+// runRawDeduped is runRaw running every distinct image once, the outputs are copied to all duplicates
+func (s *TaggerSession) runRawDeduped(images []image.Image, opts RunOptions) ([][]float32, error) {
+	unique, positions := dedupeImages(images)
+	if len(unique) == len(images) {
+		return s.runRaw(images, opts)
+	}
+
+	out, err := s.runRaw(unique, opts)
+	if err != nil {
+		var imageErr *ImageError
+		if errors.As(err, &imageErr) {
+			imageErr.Index = slices.Index(positions, imageErr.Index)
+		}
+		return nil, err
+	}
+
+	raw := make([][]float32, len(images))
+	for i, j := range positions {
+		raw[i] = slices.Clone(out[j])
+	}
+
+	return raw, nil
+}
@@ -0,0 +1,137 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// pathsGroupSize is the minimum amount of images decoded at once by RunPaths
+const pathsGroupSize = 32
+
+// imageExtensions are the extensions tagged by RunDir when RunOptions.Extensions is not set,
+// the ones of the formats registered by default
+var imageExtensions = []string{".jpg", ".jpeg", ".png"}
+
+// RunPaths tags the images at paths, returning the predictions and errors keyed by path.
+//
+// Images are decoded and tagged in small groups so only a bounded amount of them is in memory at once.
+// Images with a region in RunOptions.ROIs are cropped to it first. Like with RunEach, an image that fails
+// only fails its own path.
+func (s *TaggerSession) RunPaths(paths []string, opts RunOptions) (map[string]Predictions, map[string]error) {
+	results := make(map[string]Predictions, len(paths))
+	errs := map[string]error{}
+
+	group, err := s.chunkSize(pathsGroupSize, opts.MaxBatch)
+	if err != nil {
+		for _, path := range paths {
+			errs[path] = err
+		}
+		return results, errs
+	}
+	group = max(group, pathsGroupSize)
+
+	for chunk := range slices.Chunk(paths, group) {
+		images := make([]image.Image, 0, len(chunk))
+		decoded := make([]string, 0, len(chunk))
+		for _, path := range chunk {
+			img, err := decodeFile(path)
+			if err != nil {
+				errs[path] = err
+				continue
+			}
+
+			if roi, ok := opts.ROIs[path]; ok {
+				if roi.Empty() || !roi.In(img.Bounds()) {
+					errs[path] = fmt.Errorf("%s: ROI %v is empty or outside of the image bounds %v", path, roi, img.Bounds())
+					continue
+				}
+				img = imaging.Crop(img, roi)
+			}
+
+			images = append(images, img)
+			decoded = append(decoded, path)
+		}
+
+		if len(images) == 0 {
+			continue
+		}
+
+		predictions, imageErrs := s.RunEach(images, opts)
+		for i, path := range decoded {
+			if imageErrs[i] != nil {
+				errs[path] = fmt.Errorf("%s: %w", path, imageCause(imageErrs[i]))
+				continue
+			}
+			results[path] = predictions[i]
+		}
+	}
+
+	return results, errs
+}
+
+// RunDir tags every image in dir (and its subdirectories if recursive), see RunPaths.
+//
+// Only files with one of RunOptions.Extensions are tagged, jpg, jpeg and png by default.
+func (s *TaggerSession) RunDir(dir string, recursive bool, opts RunOptions) (map[string]Predictions, map[string]error, error) {
+	paths, err := imagePaths(dir, recursive, opts.Extensions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, errs := s.RunPaths(paths, opts)
+	return results, errs, nil
+}
+
+// imagePaths returns the files in dir (and its subdirectories if recursive) with one of exts,
+// matched case-insensitively with or without the leading dot, imageExtensions when exts is empty
+func imagePaths(dir string, recursive bool, exts []string) ([]string, error) {
+	extensions := imageExtensions
+	if len(exts) != 0 {
+		extensions = make([]string, len(exts))
+		for i, ext := range exts {
+			extensions[i] = "." + strings.TrimPrefix(strings.ToLower(ext), ".")
+		}
+	}
+
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if slices.Contains(extensions, strings.ToLower(filepath.Ext(path))) {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while walking directory %s: %w", dir, err)
+	}
+
+	return paths, nil
+}
+
+// RunGlob tags every file matching pattern (see filepath.Match for the syntax), see RunPaths.
+func (s *TaggerSession) RunGlob(pattern string, opts RunOptions) (map[string]Predictions, map[string]error, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
+	}
+
+	results, errs := s.RunPaths(paths, opts)
+	return results, errs, nil
+}
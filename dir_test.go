@@ -0,0 +1,91 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestImagePaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.jpg", "b.PNG", "c.JpEg", "d.webp", "e.txt", "sub/f.png", "sub/g.WEBP"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		recursive bool
+		exts      []string
+		want      []string
+	}{
+		{"defaults", false, nil, []string{"a.jpg", "b.PNG", "c.JpEg"}},
+		{"defaults recursive", true, nil, []string{"a.jpg", "b.PNG", "c.JpEg", "sub/f.png"}},
+		{"with and without dot", true, []string{"WEBP", ".png"}, []string{"b.PNG", "d.webp", "sub/f.png", "sub/g.WEBP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := imagePaths(dir, tt.recursive, tt.exts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := make([]string, len(tt.want))
+			for i, name := range tt.want {
+				want[i] = filepath.Join(dir, name)
+			}
+			if !slices.Equal(paths, want) {
+				t.Errorf("got %v, want %v", paths, want)
+			}
+		})
+	}
+}
+
+func TestRunPathsPerFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.png")
+	large := filepath.Join(dir, "large.png")
+	broken := filepath.Join(dir, "broken.png")
+	writePNG(t, small, 32)
+	writePNG(t, large, 96)
+	os.WriteFile(broken, []byte("not a png"), 0o644)
+
+	s := destroyedSession()
+	results, errs := s.RunPaths([]string{small, large, broken}, RunOptions{MinResolution: 64})
+
+	if len(results) != 0 || len(errs) != 3 {
+		t.Fatalf("got %d results and %d errors, want 3 errors", len(results), len(errs))
+	}
+	if !errors.Is(errs[small], ErrLowResolution) {
+		t.Errorf("small: got %v, want ErrLowResolution", errs[small])
+	}
+	if !errors.Is(errs[large], ErrSessionDestroyed) {
+		t.Errorf("large: got %v, want the run error", errs[large])
+	}
+	if errors.Is(errs[broken], ErrSessionDestroyed) || errors.Is(errs[broken], ErrLowResolution) {
+		t.Errorf("broken: got %v, want the decode error", errs[broken])
+	}
+}
+
+func writePNG(t *testing.T, path string, size int) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatal(err)
+	}
+}
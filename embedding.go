@@ -0,0 +1,67 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ErrNoEmbedding is returned by RunWithEmbeddings when the session was created without Options.EmbeddingOutput
+var ErrNoEmbedding = errors.New("session has no embedding output, set Options.EmbeddingOutput")
+
+// resolveEmbedding returns the output named name, which must be a float [batch, features] tensor
+func resolveEmbedding(outputs []ort.InputOutputInfo, name string) (ort.InputOutputInfo, error) {
+	available := make([]string, len(outputs))
+	for i, output := range outputs {
+		available[i] = output.Name
+		if output.Name != name {
+			continue
+		}
+
+		if len(output.Dimensions) != 2 || output.Dimensions[1] <= 0 {
+			return ort.InputOutputInfo{}, fmt.Errorf(
+				"unsupported shape %s of embedding output %s, expected [batch, features]",
+				output.Dimensions,
+				name,
+			)
+		}
+
+		return output, nil
+	}
+
+	return ort.InputOutputInfo{}, fmt.Errorf(
+		"model has no output named %s, available outputs are: %s",
+		name,
+		strings.Join(available, ", "),
+	)
+}
+
+// RunWithEmbeddings is RunWithOptions also returning the embedding of every image read from
+// Options.EmbeddingOutput, useful for similarity search and clustering.
+//
+// RunOptions.Cache, Dedupe and TTA are not used.
+func (s *TaggerSession) RunWithEmbeddings(images []image.Image, opts RunOptions) ([]Predictions, [][]float32, error) {
+	if s.embedding == nil {
+		return nil, nil, ErrNoEmbedding
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, embeddings, err := s.runChunks(len(images), opts, func(i int) ([]float32, error) {
+		return s.prepareImage(images[i], opts)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	predictions, err := s.predictionsFromRaw(raw, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return predictions, embeddings, nil
+}
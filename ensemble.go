@@ -0,0 +1,135 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+)
+
+// EnsembleMode is how the outputs of the sessions of an Ensemble are combined
+type EnsembleMode int
+
+const (
+	// EnsembleMean averages the scores of every session, weighted by the weight of each session
+	EnsembleMean EnsembleMode = iota
+	// EnsembleMax takes the highest score of every session, the weights are ignored
+	EnsembleMax
+)
+
+// Ensemble runs every image through multiple sessions sharing the same tags
+// and combines their scores before applying the thresholds
+type Ensemble struct {
+	Mode     EnsembleMode
+	sessions []*TaggerSession
+	// weights are the weights of every session, they are normalized when combining the outputs
+	weights []float32
+}
+
+// NewEnsemble creates an Ensemble with the provided sessions, all of them must have the same tags in the same order
+// and a weight of 1, add sessions with other weights with AddSession.
+//
+// The sessions are not owned by the Ensemble, destroy them yourself when done.
+func NewEnsemble(mode EnsembleMode, sessions ...*TaggerSession) (*Ensemble, error) {
+	if len(sessions) == 0 {
+		return nil, errors.New("an ensemble needs at least one session")
+	}
+
+	e := &Ensemble{Mode: mode}
+	for _, session := range sessions {
+		if err := e.AddSession(session, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// AddSession adds session to the ensemble with weight, which must be positive.
+// The weights are normalized to sum 1 so only their ratio matters, a weight of 2 counts twice as a weight of 1
+func (e *Ensemble) AddSession(session *TaggerSession, weight float32) error {
+	if !(weight > 0) {
+		return fmt.Errorf("invalid weight %g, it must be positive", weight)
+	}
+	if len(e.sessions) != 0 && !slices.Equal(session.names, e.sessions[0].names) {
+		return fmt.Errorf("session %d has different tags than session 0", len(e.sessions))
+	}
+
+	e.sessions = append(e.sessions, session)
+	e.weights = append(e.weights, weight)
+
+	return nil
+}
+
+// Run tags the images with every session and combines their outputs according to the Mode
+func (e *Ensemble) Run(images []image.Image, opts RunOptions) ([]Predictions, error) {
+	weights := e.normalizedWeights()
+
+	var combined [][]float32
+	for i, session := range e.sessions {
+		raw, err := session.RunRaw(images, opts)
+		if err != nil {
+			return nil, fmt.Errorf("session %d: %w", i, err)
+		}
+
+		combined = combineOutputs(e.Mode, combined, raw, weights[i])
+	}
+
+	first := e.sessions[0]
+	first.mu.Lock()
+	defer first.mu.Unlock()
+
+	predictions := make([]Predictions, 0, len(combined))
+	for i, data := range combined {
+		if err := checkFinite(data, opts.ZeroNonFinite); err != nil {
+			return nil, &ImageError{Index: i, Err: err}
+		}
+
+		predictions = append(predictions, first.buildPredictions(data, opts))
+	}
+
+	return predictions, nil
+}
+
+// normalizedWeights returns the weights of the sessions scaled to sum 1
+func (e *Ensemble) normalizedWeights() []float32 {
+	var total float32
+	for _, weight := range e.weights {
+		total += weight
+	}
+
+	weights := make([]float32, len(e.weights))
+	for i, weight := range e.weights {
+		weights[i] = weight / total
+	}
+
+	return weights
+}
+
+// combineOutputs adds the raw outputs of a session with its normalized weight to the combined outputs of the
+// previous sessions according to mode, combined is nil for the first session. raw may be modified and returned
+func combineOutputs(mode EnsembleMode, combined, raw [][]float32, weight float32) [][]float32 {
+	if combined == nil {
+		if mode != EnsembleMax {
+			for _, data := range raw {
+				for k := range data {
+					data[k] *= weight
+				}
+			}
+		}
+		return raw
+	}
+
+	for j, data := range raw {
+		for k, pred := range data {
+			switch mode {
+			case EnsembleMax:
+				combined[j][k] = max(combined[j][k], pred)
+			default:
+				combined[j][k] += pred * weight
+			}
+		}
+	}
+
+	return combined
+}
@@ -0,0 +1,55 @@
+package gotagger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEnsembleWeights(t *testing.T) {
+	e := &Ensemble{weights: []float32{1, 3}}
+	weights := e.normalizedWeights()
+	if weights[0] != 0.25 || weights[1] != 0.75 {
+		t.Fatalf("normalized weights: got %v, want [0.25 0.75]", weights)
+	}
+
+	outputs := [][][]float32{
+		{{0.2, 0.8}, {1, 0}},
+		{{0.6, 0.4}, {0, 1}},
+	}
+	tests := []struct {
+		mode EnsembleMode
+		want [][]float32
+	}{
+		{EnsembleMean, [][]float32{{0.5, 0.5}, {0.25, 0.75}}},
+		{EnsembleMax, [][]float32{{0.6, 0.8}, {1, 1}}},
+	}
+
+	for _, tt := range tests {
+		var combined [][]float32
+		for i, raw := range outputs {
+			// combineOutputs modifies the outputs, every mode gets its own copy
+			cloned := make([][]float32, len(raw))
+			for j, data := range raw {
+				cloned[j] = append([]float32(nil), data...)
+			}
+			combined = combineOutputs(tt.mode, combined, cloned, weights[i])
+		}
+
+		for j := range tt.want {
+			for k := range tt.want[j] {
+				if math.Abs(float64(combined[j][k]-tt.want[j][k])) > 1e-6 {
+					t.Errorf("mode %d: got %v, want %v", tt.mode, combined, tt.want)
+				}
+			}
+		}
+	}
+}
+
+func TestEnsembleAddSessionWeight(t *testing.T) {
+	e := &Ensemble{}
+	for _, weight := range []float32{0, -1, float32(math.NaN())} {
+		if err := e.AddSession(&TaggerSession{}, weight); err == nil {
+			t.Errorf("weight %v: expected an error", weight)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTensorCreate is matched by a RunError that failed while creating an input or output tensor
+	ErrTensorCreate = errors.New("error creating tensor")
+	// ErrSessionRun is matched by a RunError that failed while running the ORT session
+	ErrSessionRun = errors.New("error running session")
+	// ErrSessionDestroyed is returned when running a session after calling Destroy
+	ErrSessionDestroyed = errors.New("session is destroyed")
+	// ErrChunkTimeout is matched by a RunError whose chunk took longer than RunOptions.ChunkTimeout
+	ErrChunkTimeout = errors.New("chunk timed out")
+)
+
+// Phase is the step of the inference where a RunError happened
+type Phase int
+
+const (
+	// PhaseInputTensor is the creation of the input tensor
+	PhaseInputTensor Phase = iota
+	// PhaseOutputTensor is the creation of the output tensor
+	PhaseOutputTensor
+	// PhaseRun is the ORT session run
+	PhaseRun
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseInputTensor:
+		return "creating input tensor"
+	case PhaseOutputTensor:
+		return "creating output tensor"
+	case PhaseRun:
+		return "running session"
+	}
+	return fmt.Sprintf("Phase(%d)", int(p))
+}
+
+// RunError is returned when ORT fails during inference.
+//
+// It matches ErrTensorCreate or ErrSessionRun with errors.Is depending on the phase,
+// and the ORT error is available through errors.Unwrap/errors.As.
+type RunError struct {
+	// Phase is the step where the error happened
+	Phase Phase
+	// Chunk is the index of the batch that failed
+	Chunk int
+	// Start and End are the range [Start, End) of the indexes of the images in the failed batch
+	Start int
+	End   int
+	// Err is the underlying ORT error
+	Err error
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf(
+		"chunk %d (images %d to %d): error ocurred when %s: %v",
+		e.Chunk,
+		e.Start,
+		e.End-1,
+		e.Phase,
+		e.Err,
+	)
+}
+
+// Unwrap returns the sentinel error of the phase and the underlying ORT error
+func (e *RunError) Unwrap() []error {
+	sentinel := ErrSessionRun
+	if e.Phase != PhaseRun {
+		sentinel = ErrTensorCreate
+	}
+	return []error{sentinel, e.Err}
+}
+
+// ImageError is returned when a single image of a batch fails, like an invalid image or a non finite output
+type ImageError struct {
+	// Index is the index of the image in the input
+	Index int
+	Err   error
+}
+
+func (e *ImageError) Error() string {
+	return fmt.Sprintf("image %d: %v", e.Index, e.Err)
+}
+
+func (e *ImageError) Unwrap() error {
+	return e.Err
+}
+
+// withIndex returns err as an ImageError of the image at index, replacing the index of an ImageError
+func withIndex(err error, index int) error {
+	var imageErr *ImageError
+	if errors.As(err, &imageErr) {
+		return &ImageError{Index: index, Err: imageErr.Err}
+	}
+
+	return &ImageError{Index: index, Err: err}
+}
+
+// imageCause returns the error of an ImageError, the index of the image in a group is meaningless to callers
+// keying their errors by something else. Other errors are returned as is
+func imageCause(err error) error {
+	if imageErr, ok := err.(*ImageError); ok {
+		return imageErr.Err
+	}
+
+	return err
+}
+
+// firstError returns the first non-nil error of errs
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,24 +1,35 @@
 package gotagger
 
 import (
+	"bufio"
+	"bytes"
 	"cmp"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
+	"log/slog"
 	"maps"
 	"math"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/disintegration/imaging"
 
 	"github.com/go-gota/gota/dataframe"
+	"github.com/go-gota/gota/series"
 	ort "github.com/yalue/onnxruntime_go"
 )
 
+// kaomojis are the tags that keep their underscores, see DefaultKaomojis
 var kaomojis = map[string]struct{}{
 	"0_0":     {},
 	"(o)_(o)": {},
@@ -41,75 +52,443 @@ var kaomojis = map[string]struct{}{
 	"||_||":   {},
 }
 
+// DefaultKaomojis returns a copy of the built-in kaomoji tags, the face emoticons of the WD datasets
+// whose underscores are not replaced with spaces. Add to it to extend the set passed to Options.Kaomojis
+func DefaultKaomojis() map[string]struct{} {
+	return maps.Clone(kaomojis)
+}
+
 const (
 	// DefaultGeneralThreshold is the default threshold for all general tags
 	DefaultGeneralThreshold float32 = 0.35
 	// DefaultCharacterThreshold is the default threshold for all character tags
 	DefaultCharacterThreshold float32 = 0.85
+
+	// characterMCutFloor is the minimum threshold for character tags when using mcut
+	characterMCutFloor float32 = 0.15
 )
 
 type modelTags struct {
-	names            []string
+	names []string
+	// rawNames are the names as found in the tags dataset, before replacing underscores
+	rawNames         []string
 	ratingIndexes    []int
 	generalIndexes   []int
 	characterIndexes []int
+	// artistIndexes, copyrightIndexes and metaIndexes are only set by datasets with those categories,
+	// they are not part of Predictions
+	artistIndexes    []int
+	copyrightIndexes []int
+	metaIndexes      []int
+	// counts are the post counts of every tag, nil when the dataset has no count column
+	counts []int
+	// tagsPath is the tags dataset the tags were last loaded from
+	tagsPath string
 }
 
 // TaggerSession is the representation of the ORT session for this tagger
 type TaggerSession struct {
-	modelTags
-	input      ort.Shape
-	output     ort.Shape
-	targetSize int
-	batchSize  int
-	Session    *ort.DynamicSession[float32, float32]
+	// modelTags is guarded by mu, it is a pointer so ReloadTags on a copy of the session is seen by all of them
+	*modelTags
+	input  ort.Shape
+	output ort.Shape
+	heads  []ort.Shape
+	// embedding is the shape of the embedding output, nil without Options.EmbeddingOutput
+	embedding     ort.Shape
+	targetSize    int
+	batchSize     int
+	modelPath     string
+	metadata      map[string]string
+	float16Input  bool
+	float16Output bool
+	// channelOrder is never ChannelOrderAuto
+	channelOrder ChannelOrder
+	tagsFormat   tagsFormat
+	// strictCategories is Options.StrictCategories, also applied by ReloadTags
+	strictCategories bool
+	advanced         *ort.DynamicAdvancedSession
+	logger           *slog.Logger
+	// pingInput is the preprocessed black image of Ping
+	pingInput []float32
+	// defaults are the options of RunDefault
+	defaults RunOptions
+	// mu guards modelTags and serializes the ORT calls, it is a pointer so copies of the session share it
+	mu *sync.Mutex
+	// pending tracks the runs abandoned by RunOptions.ChunkTimeout that are still running
+	pending *sync.WaitGroup
+	// destroyed is guarded by mu, it is a pointer like mu so destroying a copy of the session is seen by all of them
+	destroyed *bool
+	// Session is the float32 ORT session, it is nil when the session was created with Options.Float16,
+	// Options.Float16Output or a Provider other than ProviderCPU
+	Session *ort.DynamicSession[float32, float32]
+}
+
+// tagsFormat are the settings of Options used to read the tags dataset
+type tagsFormat struct {
+	displayColumn string
+	delimiter     rune
+	ratingsPath   string
+	categoryMap   map[string]Category
+	// kaomojis defaults to the built-in set when nil
+	kaomojis map[string]struct{}
+}
+
+// readCSV parses a dataset with the delimiter of the format, stringColumns are read as is
+// instead of letting dataframe guess their type
+func (f tagsFormat) readCSV(r io.Reader, stringColumns ...string) dataframe.DataFrame {
+	delimiter := f.delimiter
+	if delimiter == 0 {
+		delimiter = ','
+	}
+
+	types := make(map[string]series.Type, len(stringColumns))
+	for _, column := range stringColumns {
+		types[column] = series.String
+	}
+
+	return dataframe.ReadCSV(r, dataframe.WithDelimiter(delimiter), dataframe.WithTypes(types))
+}
+
+// normalizeCategory returns the category code of record, integer codes written as floats like "9.0" become "9"
+func normalizeCategory(record string) string {
+	if f, err := strconv.ParseFloat(record, 64); err == nil && f == math.Trunc(f) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+
+	return strings.TrimSpace(record)
+}
+
+// loadTags reads the tags dataset at tagsPath, gzip compressed files are detected and decompressed
+func loadTags(tagsPath string, format tagsFormat) (modelTags, error) {
+	r, closeFile, err := openDataset(tagsPath)
+	if err != nil {
+		return modelTags{}, err
+	}
+	defer closeFile()
+
+	tags, err := readTags(r, format)
+	if err != nil {
+		return modelTags{}, err
+	}
+
+	if format.ratingsPath != "" {
+		if err := tags.loadRatings(format.ratingsPath, format); err != nil {
+			return modelTags{}, err
+		}
+	}
+
+	return tags, nil
 }
 
-func loadTags(tagsPath string) (modelTags, error) {
-	csvFile, err := os.Open(tagsPath)
+// openDataset opens the CSV at path, decompressing it if it is gzip compressed
+func openDataset(path string) (io.Reader, func(), error) {
+	csvFile, err := os.Open(path)
 	if err != nil {
-		return modelTags{}, fmt.Errorf("error while trying to open file %s: %w", tagsPath, err)
+		return nil, nil, fmt.Errorf("error while trying to open file %s: %w", path, err)
 	}
-	defer csvFile.Close()
 
-	df := dataframe.ReadCSV(csvFile)
+	br := bufio.NewReader(csvFile)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			csvFile.Close()
+			return nil, nil, fmt.Errorf("error while decompressing file %s: %w", path, err)
+		}
+
+		return gz, func() {
+			gz.Close()
+			csvFile.Close()
+		}, nil
+	}
+
+	return br, func() { csvFile.Close() }, nil
+}
+
+// gzipMagic are the first bytes of a gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readTags parses a tags dataset, a CSV with at least the name and category columns
+func readTags(r io.Reader, format tagsFormat) (modelTags, error) {
+	df := format.readCSV(r, "name", "category", format.displayColumn)
 	nameCol := df.Col("name").Records()
 	names := make([]string, len(nameCol))
 
+	var displayCol []string
+	if format.displayColumn != "" && slices.Contains(df.Names(), format.displayColumn) {
+		displayCol = df.Col(format.displayColumn).Records()
+	}
+
+	exempt := format.kaomojis
+	if exempt == nil {
+		exempt = kaomojis
+	}
+
 	for i, record := range nameCol {
-		if _, ok := kaomojis[record]; !ok {
+		if displayCol != nil && displayCol[i] != "" && displayCol[i] != "NaN" {
+			names[i] = displayCol[i]
+		} else if _, ok := exempt[record]; !ok {
 			names[i] = strings.ReplaceAll(record, "_", " ")
 		} else {
 			names[i] = record
 		}
 	}
 
-	categoryCol := df.Col("category").Records()
+	tags := modelTags{names: names, rawNames: nameCol}
 
-	var (
-		ratingIndexes    []int
-		generalIndexes   []int
-		characterIndexes []int
-	)
+	codes := format.categoryMap
+	if codes == nil {
+		codes = categoryCodes
+	}
+
+	var unknown []string
+	for i, record := range df.Col("category").Records() {
+		category, ok := codes[record]
+		if !ok {
+			category, ok = codes[normalizeCategory(record)]
+		}
+		if !ok {
+			if !slices.Contains(unknown, record) {
+				unknown = append(unknown, record)
+			}
+			continue
+		}
 
-	for i, record := range categoryCol {
-		switch record {
-		case "9":
-			ratingIndexes = append(ratingIndexes, i)
-		case "0":
-			generalIndexes = append(generalIndexes, i)
-		case "4":
-			characterIndexes = append(characterIndexes, i)
+		switch category {
+		case CategoryRating:
+			tags.ratingIndexes = append(tags.ratingIndexes, i)
+		case CategoryGeneral:
+			tags.generalIndexes = append(tags.generalIndexes, i)
+		case CategoryCharacter:
+			tags.characterIndexes = append(tags.characterIndexes, i)
+		case CategoryArtist:
+			tags.artistIndexes = append(tags.artistIndexes, i)
+		case CategoryCopyright:
+			tags.copyrightIndexes = append(tags.copyrightIndexes, i)
+		case CategoryMeta:
+			tags.metaIndexes = append(tags.metaIndexes, i)
 		}
 	}
 
-	return modelTags{names, ratingIndexes, generalIndexes, characterIndexes}, nil
+	if len(unknown) != 0 {
+		return modelTags{}, fmt.Errorf(
+			"unknown categories %s in the tags dataset, map them with Options.CategoryMap",
+			strings.Join(unknown, ", "),
+		)
+	}
+
+	if slices.Contains(df.Names(), "count") {
+		countCol := df.Col("count").Records()
+		tags.counts = make([]int, len(countCol))
+
+		for i, record := range countCol {
+			count, err := strconv.Atoi(record)
+			if err != nil {
+				return modelTags{}, fmt.Errorf("invalid count %q of tag %s: %w", record, nameCol[i], err)
+			}
+			tags.counts[i] = count
+		}
+	}
+
+	return tags, nil
 }
 
-// New creates a new TaggerSession with the provided model and tags dataset path.
+// ReloadTags loads the tags dataset from tagsPath and replaces the tags of the session and every copy of it,
+// the ORT session is kept as is.
 //
-// It is important to initialize and set the shared library for ORT before calling this function.
+// The new tags must have the same amount of tags as the model output, their categories are checked like
+// NewWithOptions does with Options.StrictCategories. It is safe to call while Run is in progress.
+func (s *TaggerSession) ReloadTags(tagsPath string) error {
+	tags, err := loadTags(tagsPath, s.tagsFormat)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTagCount(s.output, len(tags.names)); err != nil {
+		return fmt.Errorf("tags dataset %s has %w", tagsPath, err)
+	}
+
+	if err := checkTagCategories(&tags, s.strictCategories, s.logger); err != nil {
+		return err
+	}
+
+	tags.tagsPath = tagsPath
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*s.modelTags = tags
+
+	return nil
+}
+
+// checkTagCount returns an error when the model with the output shape doesn't output one score per tag,
+// models with a dynamic output size accept any count
+func checkTagCount(output ort.Shape, tags int) error {
+	if outSize := output[len(output)-1]; outSize > 0 && int64(tags) != outSize {
+		return fmt.Errorf("%d tags but the model outputs %d", tags, outSize)
+	}
+
+	return nil
+}
+
+// New creates a new TaggerSession with the provided model and tags dataset path,
+// the dataset must have as many tags as the model outputs.
+//
+// It is important to initialize and set the shared library for ORT before calling this function, see InitRuntime.
 func New(modelPath string, tagsPath string) (TaggerSession, error) {
+	return NewWithOptions(modelPath, tagsPath, Options{})
+}
+
+// NewWithOptions is the same as New but allows configuring how the session is created
+func NewWithOptions(modelPath string, tagsPath string, opts Options) (TaggerSession, error) {
+	format := newTagsFormat(opts)
+
+	return newWithTags(modelPath, opts, format, func() (modelTags, error) {
+		tags, err := loadTags(tagsPath, format)
+		tags.tagsPath = tagsPath
+		return tags, err
+	})
+}
+
+// NewWithTagFiles is the same as NewWithOptions with the tags split in multiple datasets, like one for the
+// general tags and another for the character tags. They are concatenated in order, so the tags of the first
+// dataset are the first outputs of the model, and together they must have as many tags as the model outputs.
+//
+// Options.RatingsPath is applied to the concatenated tags.
+func NewWithTagFiles(modelPath string, tagsPaths []string, opts Options) (TaggerSession, error) {
+	if len(tagsPaths) == 0 {
+		return TaggerSession{}, errors.New("at least one tags dataset is needed")
+	}
+
+	format := newTagsFormat(opts)
+	return newWithTags(modelPath, opts, format, func() (modelTags, error) {
+		tags, err := loadTagFiles(tagsPaths, format)
+		tags.tagsPath = strings.Join(tagsPaths, string(os.PathListSeparator))
+		return tags, err
+	})
+}
+
+// newTagsFormat returns the settings of opts used to read the tags dataset
+func newTagsFormat(opts Options) tagsFormat {
+	return tagsFormat{
+		displayColumn: opts.DisplayColumn,
+		delimiter:     opts.Delimiter,
+		ratingsPath:   opts.RatingsPath,
+		categoryMap:   opts.CategoryMap,
+		kaomojis:      opts.Kaomojis,
+	}
+}
+
+// loadTagFiles reads every tags dataset of paths and concatenates them in order, then loads the ratings of format
+func loadTagFiles(paths []string, format tagsFormat) (modelTags, error) {
+	perFile := format
+	perFile.ratingsPath = ""
+
+	var combined modelTags
+	withCounts := true
+	for _, path := range paths {
+		tags, err := loadTags(path, perFile)
+		if err != nil {
+			return modelTags{}, err
+		}
+
+		offset := len(combined.names)
+		combined.names = append(combined.names, tags.names...)
+		combined.rawNames = append(combined.rawNames, tags.rawNames...)
+		for _, group := range []struct {
+			combined *[]int
+			indexes  []int
+		}{
+			{&combined.ratingIndexes, tags.ratingIndexes},
+			{&combined.generalIndexes, tags.generalIndexes},
+			{&combined.characterIndexes, tags.characterIndexes},
+			{&combined.artistIndexes, tags.artistIndexes},
+			{&combined.copyrightIndexes, tags.copyrightIndexes},
+			{&combined.metaIndexes, tags.metaIndexes},
+		} {
+			for _, index := range group.indexes {
+				*group.combined = append(*group.combined, offset+index)
+			}
+		}
+
+		// counts are only kept when every dataset has them
+		withCounts = withCounts && tags.counts != nil
+		combined.counts = append(combined.counts, tags.counts...)
+	}
+	if !withCounts {
+		combined.counts = nil
+	}
+
+	if format.ratingsPath != "" {
+		if err := combined.loadRatings(format.ratingsPath, format); err != nil {
+			return modelTags{}, err
+		}
+	}
+
+	if err := combined.checkIndexes(); err != nil {
+		return modelTags{}, fmt.Errorf("tags datasets %s don't line up: %w", strings.Join(paths, ", "), err)
+	}
+
+	return combined, nil
+}
+
+// checkIndexes returns an error unless every tag is in exactly one category
+func (t *modelTags) checkIndexes() error {
+	categories := make([]int, len(t.names))
+	for _, indexes := range [][]int{
+		t.ratingIndexes,
+		t.generalIndexes,
+		t.characterIndexes,
+		t.artistIndexes,
+		t.copyrightIndexes,
+		t.metaIndexes,
+	} {
+		for _, index := range indexes {
+			if index < 0 || index >= len(t.names) {
+				return fmt.Errorf("tag index %d is outside of the %d tags", index, len(t.names))
+			}
+			categories[index]++
+		}
+	}
+
+	for index, count := range categories {
+		if count == 0 {
+			return fmt.Errorf("tag %d (%s) has no category", index, t.names[index])
+		}
+		if count > 1 {
+			return fmt.Errorf("tag %d (%s) is in %d categories", index, t.names[index], count)
+		}
+	}
+
+	return nil
+}
+
+// CloneWithOptions creates a new session on the same model with its own ORT session and opts,
+// the tags are shared with s instead of being read again.
+//
+// The options of the tags dataset (DisplayColumn, Delimiter, RatingsPath, CategoryMap and Kaomojis) are taken from s.
+func (s *TaggerSession) CloneWithOptions(opts Options) (TaggerSession, error) {
+	session, err := newWithTags(s.modelPath, opts, s.tagsFormat, func() (modelTags, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		return *s.modelTags, nil
+	})
+	if err != nil {
+		return TaggerSession{}, err
+	}
+
+	return session, nil
+}
+
+// newWithTags creates a session for the model at modelPath, loadTags is called once the ORT session exists.
+// Every constructor goes through it, so the tags are always checked against the model output and their categories
+func newWithTags(
+	modelPath string,
+	opts Options,
+	format tagsFormat,
+	loadTags func() (modelTags, error),
+) (TaggerSession, error) {
 	inputs, outputs, err := ort.GetInputOutputInfo(modelPath)
 	if err != nil {
 		return TaggerSession{}, fmt.Errorf(
@@ -119,67 +498,389 @@ func New(modelPath string, tagsPath string) (TaggerSession, error) {
 		)
 	}
 
-	input := inputs[0]
-	output := outputs[0]
+	input, err := resolveInput(inputs, opts.InputName)
+	if err != nil {
+		return TaggerSession{}, err
+	}
 
-	session, err := ort.NewDynamicSession[float32, float32](
-		modelPath,
-		[]string{input.Name},
-		[]string{output.Name},
-	)
+	outputNames := opts.Outputs
+	if opts.OutputName != "" {
+		if len(opts.Outputs) != 0 {
+			return TaggerSession{}, errors.New("the OutputName and Outputs options can't be used together")
+		}
+		outputNames = []string{opts.OutputName}
+	}
+
+	heads, err := resolveOutputs(outputs, outputNames)
 	if err != nil {
-		return TaggerSession{}, fmt.Errorf("error while starting new dynamic session: %w", err)
+		return TaggerSession{}, err
+	}
+
+	if err := checkDataTypes(input, heads, opts.Float16, opts.Float16 || opts.Float16Output); err != nil {
+		return TaggerSession{}, err
 	}
 
-	tags, err := loadTags(tagsPath)
+	outputNames = make([]string, len(heads))
+	headShapes := make([]ort.Shape, len(heads))
+	for i, head := range heads {
+		outputNames[i] = head.Name
+		headShapes[i] = head.Dimensions
+	}
+	outputShape := combinedShape(headShapes)
+
+	var embeddingShape ort.Shape
+	if opts.EmbeddingOutput != "" {
+		embedding, err := resolveEmbedding(outputs, opts.EmbeddingOutput)
+		if err != nil {
+			return TaggerSession{}, err
+		}
+		err = checkDataTypes(input, []ort.InputOutputInfo{embedding}, opts.Float16, opts.Float16 || opts.Float16Output)
+		if err != nil {
+			return TaggerSession{}, err
+		}
+		outputNames = append(outputNames, embedding.Name)
+		embeddingShape = embedding.Dimensions
+	}
+
+	targetSize, err := resolveTargetSize(input.Dimensions, opts.TargetSize)
+	if err != nil {
+		return TaggerSession{}, err
+	}
+
+	batchSize, err := resolveBatchSize(input.Dimensions)
+	if err != nil {
+		return TaggerSession{}, err
+	}
+
+	session, advanced, err := newSession(modelPath, []string{input.Name}, outputNames, opts)
+	if err != nil {
+		return TaggerSession{}, err
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	logger.Debug(
+		"created ORT session",
+		"model", modelPath,
+		"provider", opts.Provider,
+		"float16", opts.Float16,
+		"float16Output", opts.Float16 || opts.Float16Output,
+		"input", input.Dimensions,
+		"outputs", outputNames,
+	)
+
+	tags, err := loadSessionTags(loadTags, outputShape, opts.StrictCategories, logger, func() error {
+		if advanced != nil {
+			return advanced.Destroy()
+		}
+		return session.Destroy()
+	})
 	if err != nil {
 		return TaggerSession{}, err
 	}
 
 	inputShape := input.Dimensions
+	metadata := loadMetadata(modelPath, inputShape, outputShape)
+
+	channelOrder := opts.ChannelOrder
+	if channelOrder == ChannelOrderAuto {
+		channelOrder = detectChannelOrder(input.Name, metadata)
+	}
+
+	defaults := DefaultRunOptions()
+	if opts.RunDefaults != nil {
+		defaults = *opts.RunDefaults
+	}
 
 	return TaggerSession{
-		modelTags:  tags,
-		input:      inputShape,
-		output:     output.Dimensions,
-		batchSize:  int(inputShape[0]),
-		targetSize: int(inputShape[1]),
-		Session:    session,
+		modelTags:        &tags,
+		tagsFormat:       format,
+		strictCategories: opts.StrictCategories,
+		input:            inputShape,
+		output:           outputShape,
+		heads:            headShapes,
+		embedding:        embeddingShape,
+		batchSize:        batchSize,
+		targetSize:       targetSize,
+		modelPath:        modelPath,
+		metadata:         metadata,
+		channelOrder:     channelOrder,
+		float16Input:     opts.Float16,
+		float16Output:    opts.Float16 || opts.Float16Output,
+		advanced:         advanced,
+		logger:           logger,
+		defaults:         defaults,
+		mu:               &sync.Mutex{},
+		pending:          &sync.WaitGroup{},
+		destroyed:        new(bool),
+		Session:          session,
 	}, nil
 }
 
-func prepareInput(img image.Image, targetSize int) []float32 {
+// loadSessionTags loads the tags of a new session and checks them against its output shape and categories,
+// calling destroy to free the ORT session when they can't be used
+func loadSessionTags(
+	loadTags func() (modelTags, error),
+	output ort.Shape,
+	strict bool,
+	logger *slog.Logger,
+	destroy func() error,
+) (modelTags, error) {
+	tags, err := loadTags()
+	if err == nil {
+		if countErr := checkTagCount(output, len(tags.names)); countErr != nil {
+			err = fmt.Errorf("tags dataset %s has %w", tags.tagsPath, countErr)
+		}
+	}
+	if err == nil {
+		err = checkTagCategories(&tags, strict, logger)
+	}
+	if err != nil {
+		destroy()
+		return modelTags{}, err
+	}
+
+	return tags, nil
+}
+
+// resolveBatchSize returns the batch size of the model input, -1 for dynamic batch models
+func resolveBatchSize(inputShape ort.Shape) (int, error) {
+	if batch := inputShape[0]; batch == 0 || batch < -1 {
+		return 0, fmt.Errorf(
+			"unsupported batch size %d of input shape %s, expected -1 (dynamic) or a positive size",
+			batch,
+			inputShape,
+		)
+	}
+
+	return int(inputShape[0]), nil
+}
+
+// resolveTargetSize returns the image size of the model input, which is NHWC.
+//
+// Models with a dynamic spatial dimension (-1) need an explicit override.
+func resolveTargetSize(inputShape ort.Shape, override int) (int, error) {
+	if len(inputShape) != 4 {
+		return 0, fmt.Errorf("unsupported input shape %s, expected [batch, height, width, channels]", inputShape)
+	}
+
+	if override < 0 {
+		return 0, fmt.Errorf("invalid TargetSize %d, it must not be negative", override)
+	}
+
+	size := int(inputShape[1])
+	if size == -1 || inputShape[2] == -1 {
+		if override == 0 {
+			return 0, fmt.Errorf(
+				"model input shape %s has a dynamic image size, Options.TargetSize must be set",
+				inputShape,
+			)
+		}
+		return override, nil
+	}
+
+	if override != 0 && override != size {
+		return 0, fmt.Errorf(
+			"invalid TargetSize %d, the model has a fixed image size of %d",
+			override,
+			size,
+		)
+	}
+
+	return size, nil
+}
+
+// PreprocessImage returns img as it is fed to the model with opts, padded or cropped and resized to the
+// model size, so it can be saved and inspected. It is in RGB, the channel order only applies to the tensor
+func (s *TaggerSession) PreprocessImage(img image.Image, opts RunOptions) (image.Image, error) {
+	if err := validateImage(img, opts); err != nil {
+		return nil, err
+	}
+
+	processed := preprocess(img, s.targetSize, opts)
+	if image.Image(processed) == img {
+		processed = imaging.Clone(processed)
+	}
+
+	return processed, nil
+}
+
+func prepareInput(img image.Image, targetSize int, order ChannelOrder, opts RunOptions) []float32 {
+	processedImg := preprocess(img, targetSize, opts)
+
+	data := make([]float32, 0, 3*targetSize*targetSize)
+
+	for y := 0; y < targetSize; y++ {
+		for x := 0; x < targetSize; x++ {
+			r, g, b, _ := processedImg.At(x, y).RGBA()
+
+			if order == ChannelOrderRGB {
+				data = append(data, float32(r>>8), float32(g>>8), float32(b>>8))
+			} else {
+				data = append(data, float32(b>>8), float32(g>>8), float32(r>>8))
+			}
+		}
+	}
+
+	return data
+}
+
+// preprocess makes img a targetSize x targetSize square according to opts, the image fed to the model
+func preprocess(img image.Image, targetSize int, opts RunOptions) *image.NRGBA {
+	// oversized images are downscaled first so they are never copied at full resolution
+	if limit := opts.MaxInputDimension; limit > 0 {
+		if bounds := img.Bounds(); max(bounds.Dx(), bounds.Dy()) > limit {
+			img = imaging.Fit(img, limit, limit, opts.Resample.filter())
+		}
+	}
+
+	// every color model (CMYK, paletted, YCbCr...) goes through the same conversion before resizing and padding
+	if _, ok := img.(*image.NRGBA); !ok {
+		img = imaging.Clone(img)
+	}
+
+	var processedImg *image.NRGBA
+	switch opts.Preprocess {
+	case PreprocessCenterCrop:
+		processedImg = centerCrop(img)
+	case PreprocessFit:
+		processedImg = padToSquare(fitToSize(img, targetSize, opts.Resample.filter()), opts)
+	default:
+		processedImg = padToSquare(img, opts)
+	}
+
+	if processedImg.Bounds().Dx() != targetSize {
+		processedImg = imaging.Resize(processedImg, targetSize, targetSize, opts.Resample.filter())
+	}
+
+	return processedImg
+}
+
+// padToSquare centers img in a square canvas of its largest side filled according to opts.Padding,
+// square images are only converted to NRGBA with their origin at 0,0, they are returned as is when they already are
+func padToSquare(img image.Image, opts RunOptions) *image.NRGBA {
 	bounds := img.Bounds()
 	w := bounds.Dx()
 	h := bounds.Dy()
+	if w == h {
+		if nrgba, ok := img.(*image.NRGBA); ok && bounds.Min == (image.Point{}) {
+			return nrgba
+		}
+		return imaging.Clone(img)
+	}
 
 	maxDim := w
 	if h > maxDim {
 		maxDim = h
 	}
-	padded := imaging.New(maxDim, maxDim, color.White)
+	padded := imaging.New(maxDim, maxDim, padColor(img, opts))
 	offset := image.Pt(
 		(maxDim-bounds.Dx())/2,
 		(maxDim-bounds.Dy())/2,
 	)
 
 	processedImg := imaging.Paste(padded, img, offset)
+	if opts.Padding == PadEdgeReplicate {
+		replicateEdges(processedImg, offset, w, h)
+	}
+
+	return processedImg
+}
 
-	if maxDim != targetSize {
-		processedImg = imaging.Resize(processedImg, targetSize, targetSize, imaging.Lanczos)
+// fitToSize resizes img so its longest side is size keeping its aspect ratio, the shortest side is rounded
+// the same way whichever side is longer and is at least 1
+func fitToSize(img image.Image, size int, filter imaging.ResampleFilter) *image.NRGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w >= h {
+		return imaging.Resize(img, size, max((h*size+w/2)/w, 1), filter)
 	}
 
-	data := make([]float32, 0, 3*targetSize*targetSize)
+	return imaging.Resize(img, max((w*size+h/2)/h, 1), size, filter)
+}
 
-	for y := 0; y < targetSize; y++ {
-		for x := 0; x < targetSize; x++ {
-			r, g, b, _ := processedImg.At(x, y).RGBA()
+// padColor returns the solid color of the padding
+func padColor(img image.Image, opts RunOptions) color.Color {
+	switch opts.Padding {
+	case PadBlack:
+		return color.Black
+	case PadColor:
+		if opts.PadColor != nil {
+			return opts.PadColor
+		}
+	case PadAverageColor:
+		return averageColor(img)
+	}
+
+	return color.White
+}
 
-			data = append(data, float32(b>>8), float32(g>>8), float32(r>>8))
+// averageColor returns the mean color of img weighted by alpha so transparent pixels don't darken it,
+// fully transparent images are white
+func averageColor(img image.Image) color.Color {
+	bounds := img.Bounds()
+
+	var r, g, b, a uint64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// RGBA is alpha premultiplied so the sums are already weighted
+			pr, pg, pb, pa := img.At(x, y).RGBA()
+			r += uint64(pr)
+			g += uint64(pg)
+			b += uint64(pb)
+			a += uint64(pa)
 		}
 	}
 
-	return data
+	if a == 0 {
+		return color.White
+	}
+
+	return color.RGBA64{
+		R: uint16(r * 0xffff / a),
+		G: uint16(g * 0xffff / a),
+		B: uint16(b * 0xffff / a),
+		A: 0xffff,
+	}
+}
+
+// replicateEdges fills the padding of a square canvas holding a w x h image at offset
+// by stretching the border rows or columns of the image outwards
+func replicateEdges(canvas *image.NRGBA, offset image.Point, w, h int) {
+	side := canvas.Bounds().Dx()
+
+	if h < side {
+		top := canvas.SubImage(image.Rect(0, offset.Y, w, offset.Y+1))
+		bottom := canvas.SubImage(image.Rect(0, offset.Y+h-1, w, offset.Y+h))
+		fillWithEdge(canvas, image.Rect(0, 0, w, offset.Y), top)
+		fillWithEdge(canvas, image.Rect(0, offset.Y+h, w, side), bottom)
+	}
+
+	if w < side {
+		left := canvas.SubImage(image.Rect(offset.X, 0, offset.X+1, h))
+		right := canvas.SubImage(image.Rect(offset.X+w-1, 0, offset.X+w, h))
+		fillWithEdge(canvas, image.Rect(0, 0, offset.X, h), left)
+		fillWithEdge(canvas, image.Rect(offset.X+w, 0, side, h), right)
+	}
+}
+
+func fillWithEdge(canvas *image.NRGBA, r image.Rectangle, edge image.Image) {
+	if r.Empty() {
+		return
+	}
+
+	stretched := imaging.Resize(edge, r.Dx(), r.Dy(), imaging.NearestNeighbor)
+	draw.Draw(canvas, r, stretched, image.Point{}, draw.Src)
+}
+
+// centerCrop crops the largest centered square of img
+func centerCrop(img image.Image) *image.NRGBA {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+
+	return imaging.CropCenter(img, side, side)
 }
 
 // Predictions is the output of the Run function containing all tags
@@ -187,47 +888,49 @@ type Predictions struct {
 	General   map[string]float32
 	Rating    map[string]float32
 	Character map[string]float32
+	// GeneralThresholdUsed is the threshold applied to the general tags, after mcut if enabled
+	GeneralThresholdUsed float32
+	// CharacterThresholdUsed is the threshold applied to the character tags, after mcut and its floor if enabled
+	CharacterThresholdUsed float32
+	// RawNames maps every tag in the predictions to its name in the tags dataset (e.g. "long hair" to "long_hair"),
+	// it is only set with RunOptions.RawNames
+	RawNames map[string]string
+	// SortedGeneral are the general tags sorted like Names with their scores, it is only set with RunOptions.Sorted
+	SortedGeneral []TagScore `json:",omitempty"`
 }
 
-// Names will output the sorted General tags names
+// Names will output the sorted General tags names, tags with the same score are sorted by name
 func (p *Predictions) Names() []string {
-	q := slices.Collect(maps.Keys(p.General))
-
-	slices.SortFunc(q, func(a, b string) int {
-		return cmp.Compare(p.General[b], p.General[a])
-	})
-
-	return q
+	return sortedKeys(p.General)
 }
 
-func mcutThreshold(probs []float32) float32 {
-	if len(probs) < 2 {
-		if len(probs) == 0 {
-			return 0
-		}
-		return probs[0]
+// AllNames returns the general and character tags names sorted like Names.
+//
+// A name in both categories is listed twice unless dedupe is set, in which case it is listed once
+// at the position of its highest score.
+func (p *Predictions) AllNames(dedupe bool) []string {
+	tags := make([]TagScore, 0, len(p.General)+len(p.Character))
+	for name, score := range p.General {
+		tags = append(tags, TagScore{name, score, CategoryGeneral})
 	}
-
-	sortedProbs := make([]float32, len(probs))
-	copy(sortedProbs, probs)
-	slices.SortFunc(sortedProbs, func(a, b float32) int {
-		if a > b {
-			return -1
-		} else if a < b {
-			return 1
-		}
-		return 0
+	for name, score := range p.Character {
+		tags = append(tags, TagScore{name, score, CategoryCharacter})
+	}
+	slices.SortFunc(tags, func(a, b TagScore) int {
+		return cmp.Or(compareTags(a.Name, a.Score, b.Name, b.Score), cmp.Compare(a.Category, b.Category))
 	})
-	maxDiff := float32(0)
-	maxIndex := 0
-	for i := 0; i < len(sortedProbs)-1; i++ {
-		diff := sortedProbs[i] - sortedProbs[i+1]
-		if diff > maxDiff {
-			maxDiff = diff
-			maxIndex = i
+
+	names := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if dedupe && seen[tag.Name] {
+			continue
 		}
+		seen[tag.Name] = true
+		names = append(names, tag.Name)
 	}
-	return (sortedProbs[maxIndex] + sortedProbs[maxIndex+1]) / 2
+
+	return names
 }
 
 // Run the current session with the provided images and settings
@@ -251,108 +954,271 @@ func (s *TaggerSession) Run(
 	generalMCutEnabled bool,
 	characterMCutEnabled bool,
 ) ([]Predictions, error) {
-	predictions := make([]Predictions, 0, len(images))
-	size := int(math.Abs(float64(s.batchSize)))
-	chunks := make([][]image.Image, 0, size)
-	if s.batchSize == -1 {
-		chunks = append(chunks, images)
-	} else {
-		chunks = slices.Collect(slices.Chunk(images, s.batchSize))
+	return s.RunWithOptions(images, RunOptions{
+		GeneralThreshold:   generalThreshold,
+		CharacterThreshold: characterThreshold,
+		GeneralMCut:        generalMCutEnabled,
+		CharacterMCut:      characterMCutEnabled,
+	})
+}
+
+// RunWithOptions is the same as Run but takes all the settings in a RunOptions
+func (s *TaggerSession) RunWithOptions(images []image.Image, opts RunOptions) ([]Predictions, error) {
+	predictions, errs, err := s.runEach(images, opts)
+	if imageErr := firstError(errs); imageErr != nil {
+		return nil, imageErr
 	}
 
-	for _, chunk := range chunks {
-		imgData := make([]float32, 0, size*3*(s.targetSize^2))
+	return predictions, err
+}
 
-		for _, img := range chunk {
-			imgData = append(imgData, prepareInput(img, s.targetSize)...)
+// RunEach is the same as RunWithOptions but an image that fails only fails itself, images rejected by Validate
+// and images with a non finite output get their ImageError in errs while the others are tagged.
+//
+// Both returned slices have the same length as images, predictions[i] is empty when errs[i] is set.
+// An error failing the whole run, like an ORT error or ErrChunkTimeout, is set for every image left untagged.
+func (s *TaggerSession) RunEach(images []image.Image, opts RunOptions) ([]Predictions, []error) {
+	predictions := make([]Predictions, len(images))
+	errs := Validate(images, opts)
+
+	var indexes []int
+	for i, err := range errs {
+		if err == nil {
+			indexes = append(indexes, i)
 		}
+	}
 
-		inShape := s.input
-		if inShape[0] == -1 {
-			inShape[0] = int64(size)
+	for len(indexes) != 0 {
+		valid := make([]image.Image, len(indexes))
+		for j, i := range indexes {
+			valid[j] = images[i]
 		}
 
-		inTensor, err := ort.NewTensor(inShape, imgData)
-		if err != nil {
-			return nil, fmt.Errorf("error ocurred when creating input tensor: %w", err)
+		out, outErrs, err := s.runEach(valid, opts)
+
+		// an image failing preprocessing fails the whole run, it is dropped and the others run again
+		var imageErr *ImageError
+		if errors.As(err, &imageErr) && imageErr.Index >= 0 && imageErr.Index < len(indexes) {
+			errs[indexes[imageErr.Index]] = withIndex(imageErr, indexes[imageErr.Index])
+			indexes = slices.Delete(indexes, imageErr.Index, imageErr.Index+1)
+			continue
 		}
 
-		outShape := s.output
-		if outShape[0] == -1 {
-			outShape[0] = int64(size)
+		for j, i := range indexes {
+			switch {
+			case j >= len(out):
+				errs[i] = err
+			case outErrs[j] != nil:
+				errs[i] = withIndex(outErrs[j], i)
+			default:
+				predictions[i] = out[j]
+			}
 		}
+		break
+	}
 
-		outSize := int(outShape[1])
+	return predictions, errs
+}
 
-		outTensor, err := ort.NewEmptyTensor[float32](outShape)
-		if err != nil {
-			return nil, fmt.Errorf("error ocurred when creating output tensor: %w", err)
+// runEach runs the images through opts.Cache if set, see runPredictions
+func (s *TaggerSession) runEach(images []image.Image, opts RunOptions) ([]Predictions, []error, error) {
+	if opts.Cache != nil {
+		return s.runCached(images, opts)
+	}
+
+	return s.runPredictions(images, opts)
+}
+
+// runPredictions runs the images and applies the thresholds, errs[i] is set when the output of image i is not finite.
+// err fails the whole run, except ErrChunkTimeout which comes with the predictions of the chunks completed before it
+func (s *TaggerSession) runPredictions(images []image.Image, opts RunOptions) ([]Predictions, []error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runRaw := s.runRaw
+	if opts.Dedupe {
+		runRaw = s.runRawDeduped
+	}
+
+	raw, err := runRaw(images, opts)
+	if err != nil && !(errors.Is(err, ErrChunkTimeout) && raw != nil) {
+		return nil, nil, err
+	}
+
+	predictions, errs := s.predictionsEach(raw, opts)
+	return predictions, errs, err
+}
+
+// RunDefault runs the session with the default options of the session, see SetDefaults.
+// RunWithOptions ignores them and uses only the options it is given
+func (s *TaggerSession) RunDefault(images []image.Image) ([]Predictions, error) {
+	return s.RunWithOptions(images, s.Defaults())
+}
+
+// SetDefaults replaces the options used by RunDefault
+func (s *TaggerSession) SetDefaults(opts RunOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaults = opts
+}
+
+// Defaults returns the options used by RunDefault
+func (s *TaggerSession) Defaults() RunOptions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.defaults
+}
+
+// RunTensors runs the session with already preprocessed images, skipping the decoding and preprocessing.
+//
+// Every input must be the pixels of a targetSize x targetSize image in HWC order with values from 0 to 255,
+// which is 3*targetSize*targetSize values. The channels are in the order of the session, Config().ChannelOrder,
+// which is detected from the model or set with Options.ChannelOrder.
+func (s *TaggerSession) RunTensors(data [][]float32, opts RunOptions) ([]Predictions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	imgSize := 3 * s.targetSize * s.targetSize
+	raw, err := s.runBatches(len(data), opts, func(i int) ([]float32, error) {
+		if len(data[i]) != imgSize {
+			return nil, fmt.Errorf("input has %d values, expected %d", len(data[i]), imgSize)
 		}
 
-		err = s.Session.Run([]*ort.Tensor[float32]{inTensor}, []*ort.Tensor[float32]{outTensor})
-		if err != nil {
-			return nil, fmt.Errorf("error ocurred when running session: %w", err)
-		}
-
-		out := outTensor.GetData()
-		for i := 0; i < len(chunk); i++ {
-			data := out[outSize*(i) : outSize*(i+1)]
-
-			computedGeneralThreshold := generalThreshold
-			if generalMCutEnabled {
-				var generalProbs []float32
-				for _, index := range s.generalIndexes {
-					if index < len(data) {
-						generalProbs = append(generalProbs, data[index])
-					}
-				}
-				computedGeneralThreshold = mcutThreshold(generalProbs)
-			}
+		return data[i], nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			computedCharacterThreshold := characterThreshold
-			if characterMCutEnabled {
-				var characterProbs []float32
-				for _, index := range s.characterIndexes {
-					if index < len(data) {
-						characterProbs = append(characterProbs, data[index])
-					}
-				}
-				computedCharacterThreshold = mcutThreshold(characterProbs)
-				if computedCharacterThreshold < 0.15 {
-					computedCharacterThreshold = 0.15
-				}
-			}
+	return s.predictionsFromRaw(raw, opts)
+}
 
-			p := Predictions{
-				General:   map[string]float32{},
-				Rating:    map[string]float32{},
-				Character: map[string]float32{},
-			}
-			for index, pred := range data {
-				name := s.names[index]
-
-				if slices.Contains(s.ratingIndexes, index) {
-					p.Rating[name] = pred
-				}
-				if slices.Contains(s.generalIndexes, index) && pred > computedGeneralThreshold {
-					p.General[name] = pred
-				}
-				if slices.Contains(s.characterIndexes, index) && pred > computedCharacterThreshold {
-					p.Character[name] = pred
-				}
-			}
+// RunOne runs the session with a single image
+func (s *TaggerSession) RunOne(img image.Image, opts RunOptions) (Predictions, error) {
+	predictions, err := s.RunWithOptions([]image.Image{img}, opts)
+	if err != nil {
+		return Predictions{}, err
+	}
+
+	return predictions[0], nil
+}
+
+// TopTag tags img and returns its general tag with the highest score, regardless of the thresholds of opts
+func (s *TaggerSession) TopTag(img image.Image, opts RunOptions) (string, float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := s.runRaw([]image.Image{img}, opts)
+	if err != nil {
+		return "", 0, err
+	}
+
+	data := raw[0]
+	if err := checkFinite(data, opts.ZeroNonFinite); err != nil {
+		return "", 0, err
+	}
+	data = scaleScores(data, opts)
 
-			predictions = append(predictions, p)
+	best := -1
+	for _, index := range s.generalIndexes {
+		if index < len(data) && (best == -1 || data[index] > data[best]) {
+			best = index
 		}
+	}
+	if best == -1 {
+		return "", 0, errors.New("the tags dataset has no general tags")
+	}
+
+	return s.names[best], data[best], nil
+}
+
+// TopRatingIndex tags img and returns the tag index of its most likely rating and its score, without building
+// the Predictions maps. The name of the rating is Names()[index]
+func (s *TaggerSession) TopRatingIndex(img image.Image, opts RunOptions) (int, float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		outTensor.Destroy()
-		inTensor.Destroy()
+	raw, err := s.runRaw([]image.Image{img}, opts)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	return predictions, nil
+	data := raw[0]
+	if err := checkFinite(data, opts.ZeroNonFinite); err != nil {
+		return 0, 0, err
+	}
+
+	// the sigmoid and the temperature keep the order of the scores so only the best one is scaled
+	best := -1
+	for _, index := range s.ratingIndexes {
+		if index < len(data) && (best == -1 || data[index] > data[best]) {
+			best = index
+		}
+	}
+	if best == -1 {
+		return 0, 0, errors.New("the tags dataset has no ratings")
+	}
+
+	return best, scaleScores(data[best:best+1], opts)[0], nil
 }
 
-// Destroy the current session
+// RunGroups runs the session with every image of every group batched together,
+// the predictions are returned with the same grouping and order as groups
+func (s *TaggerSession) RunGroups(groups [][]image.Image, opts RunOptions) ([][]Predictions, error) {
+	predictions, err := s.RunWithOptions(slices.Concat(groups...), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make([][]Predictions, len(groups))
+	offset := 0
+	for i, group := range groups {
+		grouped[i] = predictions[offset : offset+len(group) : offset+len(group)]
+		offset += len(group)
+	}
+
+	return grouped, nil
+}
+
+// RunRaw runs the session and returns the raw output of every image without applying any threshold.
+//
+// Each output is indexed the same as Names, the thresholds and filters of opts are not used.
+func (s *TaggerSession) RunRaw(images []image.Image, opts RunOptions) ([][]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.runRaw(images, opts)
+}
+
+// Names returns a copy of the tag names in the order of the model output, aligned with the outputs of RunRaw
+func (s *TaggerSession) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.names)
+}
+
+// Destroy the current session, calling it more than once, on any copy of the session, is a no-op.
+// It also is on the zero TaggerSession returned with errors.
+// It blocks until the runs abandoned by RunOptions.ChunkTimeout return.
 func (s *TaggerSession) Destroy() error {
+	if s.mu == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if *s.destroyed {
+		return nil
+	}
+	*s.destroyed = true
+	s.pending.Wait()
+
+	if s.advanced != nil {
+		return s.advanced.Destroy()
+	}
 	return s.Session.Destroy()
 }
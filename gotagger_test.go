@@ -0,0 +1,257 @@
+package gotagger
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestPrepareInputColorModels(t *testing.T) {
+	cmyk := image.NewCMYK(image.Rect(0, 0, 20, 12))
+	cmykRef := image.NewNRGBA(cmyk.Bounds())
+	palette := color.Palette{color.Black, color.White, color.NRGBA{200, 30, 60, 255}, color.NRGBA{10, 120, 240, 255}}
+	paletted := image.NewPaletted(image.Rect(0, 0, 20, 12), palette)
+	palettedRef := image.NewNRGBA(paletted.Bounds())
+
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 20; x++ {
+			c := color.CMYK{C: uint8(x * 12), M: uint8(y * 20), Y: 30, K: 40}
+			cmyk.Set(x, y, c)
+			cmykRef.Set(x, y, color.NRGBAModel.Convert(c))
+
+			p := palette[(x+y)%len(palette)]
+			paletted.Set(x, y, p)
+			palettedRef.Set(x, y, color.NRGBAModel.Convert(p))
+		}
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		ref  image.Image
+	}{
+		{"cmyk", cmyk, cmykRef},
+		{"paletted", paletted, palettedRef},
+	}
+	for _, tt := range tests {
+		for _, opts := range []RunOptions{{}, {Preprocess: PreprocessFit}, {MaxInputDimension: 10}} {
+			got := prepareInput(tt.img, 16, ChannelOrderBGR, opts)
+			want := prepareInput(tt.ref, 16, ChannelOrderBGR, opts)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s with %+v: input differs from the RGB reference", tt.name, opts)
+			}
+		}
+	}
+}
+
+func TestPreprocessFitSymmetry(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	wide := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	tall := image.NewNRGBA(image.Rect(0, 0, 100, 200))
+	draw.Draw(wide, wide.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(tall, tall.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+
+	opts := RunOptions{Preprocess: PreprocessFit, Resample: ResampleNearest}
+	gotWide := preprocess(wide, 64, opts)
+	gotTall := preprocess(tall, 64, opts)
+
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			// the wide image fills rows 16 to 47 and the tall one the same columns, the rest is white padding
+			want := color.NRGBA{255, 255, 255, 255}
+			if y >= 16 && y < 48 {
+				want = red
+			}
+			if got := gotWide.NRGBAAt(x, y); got != want {
+				t.Fatalf("wide at %d,%d: got %v, want %v", x, y, got, want)
+			}
+			if got := gotTall.NRGBAAt(y, x); got != want {
+				t.Fatalf("tall at %d,%d: got %v, want %v", y, x, got, want)
+			}
+		}
+	}
+}
+
+func TestPrepareInputGrayscale(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 4)
+	}
+
+	// every channel gets the gray value
+	data := prepareInput(gray, 8, ChannelOrderBGR, RunOptions{})
+	for i, v := range gray.Pix {
+		for c := range 3 {
+			if got := data[i*3+c]; got != float32(v) {
+				t.Fatalf("pixel %d channel %d: got %v, want %v", i, c, got, v)
+			}
+		}
+	}
+}
+
+func TestPreprocessCenterCrop(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	green := color.NRGBA{0, 255, 0, 255}
+
+	// the center 10x10 square of both images is green, the edges of the longest side are red
+	wide := image.NewNRGBA(image.Rect(0, 0, 30, 10))
+	tall := image.NewNRGBA(image.Rect(0, 0, 10, 30))
+	for a := 0; a < 30; a++ {
+		for b := 0; b < 10; b++ {
+			c := red
+			if a >= 10 && a < 20 {
+				c = green
+			}
+			wide.SetNRGBA(a, b, c)
+			tall.SetNRGBA(b, a, c)
+		}
+	}
+
+	for name, img := range map[string]image.Image{"wide": wide, "tall": tall} {
+		got := preprocess(img, 10, RunOptions{Preprocess: PreprocessCenterCrop})
+		if got.Bounds() != image.Rect(0, 0, 10, 10) {
+			t.Fatalf("%s: got bounds %v, want 10x10", name, got.Bounds())
+		}
+		for y := 0; y < 10; y++ {
+			for x := 0; x < 10; x++ {
+				if c := got.NRGBAAt(x, y); c != green {
+					t.Fatalf("%s at %d,%d: got %v, want %v", name, x, y, c, green)
+				}
+			}
+		}
+	}
+}
+
+func TestPadToSquareModes(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+
+	// a 4x2 image with a red top row and a blue bottom row, centered on rows 1 and 2 of the canvas
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.SetNRGBA(x, 0, red)
+		img.SetNRGBA(x, 1, blue)
+	}
+
+	tests := []struct {
+		name        string
+		opts        RunOptions
+		top, bottom color.NRGBA
+	}{
+		{"white", RunOptions{}, color.NRGBA{255, 255, 255, 255}, color.NRGBA{255, 255, 255, 255}},
+		{"black", RunOptions{Padding: PadBlack}, color.NRGBA{0, 0, 0, 255}, color.NRGBA{0, 0, 0, 255}},
+		{
+			"color",
+			RunOptions{Padding: PadColor, PadColor: color.NRGBA{10, 20, 30, 255}},
+			color.NRGBA{10, 20, 30, 255},
+			color.NRGBA{10, 20, 30, 255},
+		},
+		{"edge replicate", RunOptions{Padding: PadEdgeReplicate}, red, blue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := padToSquare(img, tt.opts)
+			if got.Bounds() != image.Rect(0, 0, 4, 4) {
+				t.Fatalf("got bounds %v, want 4x4", got.Bounds())
+			}
+
+			for x := 0; x < 4; x++ {
+				for y, want := range []color.NRGBA{tt.top, red, blue, tt.bottom} {
+					if c := got.NRGBAAt(x, y); c != want {
+						t.Fatalf("at %d,%d: got %v, want %v", x, y, c, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPadToSquareEdgeReplicateTall(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 4))
+	for y := 0; y < 4; y++ {
+		img.SetNRGBA(0, y, red)
+		img.SetNRGBA(1, y, blue)
+	}
+
+	got := padToSquare(img, RunOptions{Padding: PadEdgeReplicate})
+	for y := 0; y < 4; y++ {
+		for x, want := range []color.NRGBA{red, red, blue, blue} {
+			if c := got.NRGBAAt(x, y); c != want {
+				t.Fatalf("at %d,%d: got %v, want %v", x, y, c, want)
+			}
+		}
+	}
+}
+
+func TestPadAverageColor(t *testing.T) {
+	// the transparent green pixels don't count towards the average
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.SetNRGBA(x, 0, color.NRGBA{200, 0, 0, 255})
+		img.SetNRGBA(x, 1, color.NRGBA{0, 255, 0, 0})
+	}
+
+	got := padToSquare(img, RunOptions{Padding: PadAverageColor})
+	if c := got.NRGBAAt(0, 0); c != (color.NRGBA{200, 0, 0, 255}) {
+		t.Errorf("padding: got %v, want {200 0 0 255}", c)
+	}
+
+	transparent := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	got = padToSquare(transparent, RunOptions{Padding: PadAverageColor})
+	if c := got.NRGBAAt(0, 0); c != (color.NRGBA{255, 255, 255, 255}) {
+		t.Errorf("transparent image padding: got %v, want white", c)
+	}
+}
+
+func TestPreprocessNearest(t *testing.T) {
+	black := color.NRGBA{0, 0, 0, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.SetNRGBA(0, 0, black)
+	img.SetNRGBA(1, 0, white)
+	img.SetNRGBA(0, 1, white)
+	img.SetNRGBA(1, 1, black)
+
+	// every pixel is copied into a 4x4 block without blending
+	got := preprocess(img, 8, RunOptions{Resample: ResampleNearest})
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if c, want := got.NRGBAAt(x, y), img.NRGBAAt(x/4, y/4); c != want {
+				t.Fatalf("at %d,%d: got %v, want %v", x, y, c, want)
+			}
+		}
+	}
+}
+
+func TestPreprocessSquareSkipsPadding(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 40))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7)
+	}
+
+	if got := padToSquare(img, RunOptions{}); got != img {
+		t.Error("padToSquare copied an NRGBA square image")
+	}
+
+	// the offset origin is moved to 0,0 without padding
+	shifted := img.SubImage(image.Rect(0, 0, 40, 40)).(*image.NRGBA)
+	shifted.Rect = shifted.Rect.Add(image.Pt(5, 5))
+	if got := padToSquare(shifted, RunOptions{}); got.Bounds() != image.Rect(0, 0, 40, 40) {
+		t.Errorf("offset square image: got bounds %v, want 40x40 at the origin", got.Bounds())
+	}
+
+	got := preprocess(img, 16, RunOptions{})
+	want := imaging.Resize(img, 16, 16, imaging.Lanczos)
+	if !slices.Equal(got.Pix, want.Pix) {
+		t.Error("square image differs from a direct resize")
+	}
+}
@@ -0,0 +1,88 @@
+package gotagger
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// float32ToFloat16 converts f to IEEE 754 half precision bits, rounding to nearest even
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127 > 15:
+		return sign | 0x7c00
+	case exp-127 < -25:
+		return sign
+	case exp-127 < -14:
+		// subnormal half
+		mant |= 0x800000
+		shift := uint32(-exp + 127 - 14 + 13)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		mid := uint32(1) << (shift - 1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := uint32(exp-127+15)<<10 | mant>>13
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		// may carry into the exponent, which still produces the right value (or infinity)
+		half++
+	}
+
+	return sign | uint16(half)
+}
+
+// float16ToFloat32 converts IEEE 754 half precision bits to a float32
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch {
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case exp == 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// subnormal half, normalize it
+		exp = 127 - 14
+		for mant&0x400 == 0 {
+			mant <<= 1
+			exp--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | exp<<23 | mant<<13)
+	}
+
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+func encodeFloat16(data []float32) []byte {
+	b := make([]byte, 2*len(data))
+	for i, f := range data {
+		binary.NativeEndian.PutUint16(b[2*i:], float32ToFloat16(f))
+	}
+	return b
+}
+
+func decodeFloat16(b []byte) []float32 {
+	data := make([]float32, len(b)/2)
+	for i := range data {
+		data[i] = float16ToFloat32(binary.NativeEndian.Uint16(b[2*i:]))
+	}
+	return data
+}
@@ -0,0 +1,61 @@
+package gotagger
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestFloat16(t *testing.T) {
+	tests := []struct {
+		f    float32
+		bits uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff},
+		{float32(math.Inf(1)), 0x7c00},
+		{float32(math.Inf(-1)), 0xfc00},
+		// the smallest subnormal and normal halves
+		{float32(math.Ldexp(1, -24)), 0x0001},
+		{float32(math.Ldexp(1, -14)), 0x0400},
+	}
+
+	for _, tt := range tests {
+		if got := float32ToFloat16(tt.f); got != tt.bits {
+			t.Errorf("float32ToFloat16(%v): got %#04x, want %#04x", tt.f, got, tt.bits)
+		}
+		if got := float16ToFloat32(tt.bits); math.Float32bits(got) != math.Float32bits(tt.f) {
+			t.Errorf("float16ToFloat32(%#04x): got %v, want %v", tt.bits, got, tt.f)
+		}
+	}
+
+	if got := float32ToFloat16(1e6); got != 0x7c00 {
+		t.Errorf("overflow: got %#04x, want infinity", got)
+	}
+	if got := float16ToFloat32(float32ToFloat16(float32(math.NaN()))); !math.IsNaN(float64(got)) {
+		t.Errorf("NaN: got %v", got)
+	}
+	// 1 + 2^-11 is halfway between 1 and the next half, it rounds to the even 1
+	if got := float32ToFloat16(1 + float32(math.Ldexp(1, -11))); got != 0x3c00 {
+		t.Errorf("round to even: got %#04x, want 0x3c00", got)
+	}
+}
+
+func TestFloat16RoundTrip(t *testing.T) {
+	data := []float32{0, 0.25, 0.5, 0.75, 1, 0.125, 0.0625}
+	if got := decodeFloat16(encodeFloat16(data)); !slices.Equal(got, data) {
+		t.Errorf("got %v, want %v", got, data)
+	}
+
+	// probabilities keep about 3 significant digits
+	for i := range 1000 {
+		p := float32(i) / 1000
+		if got := float16ToFloat32(float32ToFloat16(p)); math.Abs(float64(got-p)) > 5e-4 {
+			t.Fatalf("%v: got %v after the round trip", p, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+)
+
+// IndexedPredictions are Predictions keyed by tag index (the model output index) instead of by name,
+// so large batches don't hold a copy of every tag name per image. Resolve them with TaggerSession.Resolve
+// or TaggerSession.TagName
+type IndexedPredictions struct {
+	General                map[int]float32
+	Rating                 map[int]float32
+	Character              map[int]float32
+	GeneralThresholdUsed   float32
+	CharacterThresholdUsed float32
+}
+
+// RunIndexed is RunWithOptions returning IndexedPredictions.
+//
+// RunOptions.Cache, RawNames and Implications are not used, implied tags may not be in the tags dataset.
+func (s *TaggerSession) RunIndexed(images []image.Image, opts RunOptions) ([]IndexedPredictions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runRaw := s.runRaw
+	if opts.Dedupe {
+		runRaw = s.runRawDeduped
+	}
+
+	raw, err := runRaw(images, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.warnIgnoredOptions(opts)
+	opts.Implications = nil
+
+	predictions := make([]IndexedPredictions, len(raw))
+	for i, data := range raw {
+		if err := checkFinite(data, opts.ZeroNonFinite); err != nil {
+			return nil, &ImageError{Index: i, Err: err}
+		}
+
+		sel := s.selectTags(data, opts)
+		predictions[i] = IndexedPredictions{
+			General:                indexedScores(sel.general, sel.scores),
+			Rating:                 indexedScores(sel.rating, sel.scores),
+			Character:              indexedScores(sel.character, sel.scores),
+			GeneralThresholdUsed:   sel.generalThreshold,
+			CharacterThresholdUsed: sel.characterThreshold,
+		}
+	}
+
+	return predictions, nil
+}
+
+func indexedScores(indexes []int, scores []float32) map[int]float32 {
+	indexed := make(map[int]float32, len(indexes))
+	for _, index := range indexes {
+		indexed[index] = scores[index]
+	}
+
+	return indexed
+}
+
+// TagName returns the name of the tag at index, the same as Names()[index] without copying the names
+func (s *TaggerSession) TagName(index int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if index < 0 || index >= len(s.names) {
+		return "", fmt.Errorf("invalid tag index %d, the session has %d tags", index, len(s.names))
+	}
+
+	return s.names[index], nil
+}
+
+// Resolve converts IndexedPredictions to Predictions with the tag names of the session
+func (s *TaggerSession) Resolve(p IndexedPredictions) (Predictions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resolved := Predictions{
+		General:                make(map[string]float32, len(p.General)),
+		Rating:                 make(map[string]float32, len(p.Rating)),
+		Character:              make(map[string]float32, len(p.Character)),
+		GeneralThresholdUsed:   p.GeneralThresholdUsed,
+		CharacterThresholdUsed: p.CharacterThresholdUsed,
+	}
+	for _, group := range []struct {
+		indexed map[int]float32
+		scores  map[string]float32
+	}{
+		{p.General, resolved.General},
+		{p.Rating, resolved.Rating},
+		{p.Character, resolved.Character},
+	} {
+		for index, score := range group.indexed {
+			if index < 0 || index >= len(s.names) {
+				return Predictions{}, fmt.Errorf("invalid tag index %d, the session has %d tags", index, len(s.names))
+			}
+			group.scores[s.names[index]] = score
+		}
+	}
+
+	return resolved, nil
+}
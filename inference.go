@@ -0,0 +1,359 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// chunkSize returns how many images go into a single tensor.
+//
+// Dynamic batch models (-1) take every image at once unless maxBatch is set,
+// fixed batch models take up to their batch size and maxBatch can only lower it.
+func (s *TaggerSession) chunkSize(images int, maxBatch int) (int, error) {
+	if maxBatch < 0 {
+		return 0, fmt.Errorf("invalid MaxBatch %d, it must not be negative", maxBatch)
+	}
+
+	if s.batchSize == -1 {
+		if maxBatch > 0 {
+			return maxBatch, nil
+		}
+		return max(images, 1), nil
+	}
+
+	if maxBatch > s.batchSize {
+		return 0, fmt.Errorf(
+			"invalid MaxBatch %d, the model has a fixed batch size of %d",
+			maxBatch,
+			s.batchSize,
+		)
+	}
+	if maxBatch > 0 {
+		return maxBatch, nil
+	}
+
+	return s.batchSize, nil
+}
+
+// chunkTensors are the tensors of a chunk, they are reused by the next chunks with the same input shape
+type chunkTensors struct {
+	inShape   ort.Shape
+	in        ort.Value
+	outShapes []ort.Shape
+	outs      []ort.Value
+}
+
+// newChunkTensors creates the input and output tensors for a chunk of rows images
+func (s *TaggerSession) newChunkTensors(inShape ort.Shape, rows int) (*chunkTensors, error) {
+	in, err := s.newTensor(inShape, make([]float32, inShape.FlattenedSize()))
+	if err != nil {
+		return nil, &RunError{Phase: PhaseInputTensor, Err: err}
+	}
+
+	t := &chunkTensors{inShape: inShape, in: in, outShapes: s.headShapes(rows)}
+	if s.embedding != nil {
+		embedding := s.embedding.Clone()
+		embedding[0] = int64(rows)
+		t.outShapes = append(t.outShapes, embedding)
+	}
+	for _, outShape := range t.outShapes {
+		out, err := s.newEmptyTensor(outShape)
+		if err != nil {
+			t.destroy()
+			return nil, &RunError{Phase: PhaseOutputTensor, Err: err}
+		}
+		t.outs = append(t.outs, out)
+	}
+
+	return t, nil
+}
+
+func (t *chunkTensors) destroy() {
+	t.in.Destroy()
+	for _, out := range t.outs {
+		out.Destroy()
+	}
+}
+
+// infer copies data into the input tensor of t, runs the session and returns the flat output,
+// and the flat embeddings if the session has an embedding output
+func (s *TaggerSession) infer(t *chunkTensors, data []float32, timeout time.Duration) ([]float32, []float32, error) {
+	setTensorData(t.in, data)
+
+	if err := s.runChunk(t, timeout); err != nil {
+		return nil, nil, &RunError{Phase: PhaseRun, Err: err}
+	}
+
+	outs := make([][]float32, len(t.outs))
+	for i, outTensor := range t.outs {
+		outs[i] = tensorData(outTensor)
+	}
+
+	heads := len(s.heads)
+	var embeddings []float32
+	if len(outs) > heads {
+		embeddings = outs[heads]
+	}
+
+	return mergeHeads(outs[:heads], t.outShapes[:heads]), embeddings, nil
+}
+
+// runChunk runs the session on the tensors of t, giving up with ErrChunkTimeout after timeout when it is positive.
+//
+// ORT runs can't be canceled, so a timed out run keeps going in its goroutine which destroys t once ORT returns,
+// t must not be used after a timeout. Destroy waits for those runs before destroying the session.
+func (s *TaggerSession) runChunk(t *chunkTensors, timeout time.Duration) error {
+	if timeout <= 0 {
+		return s.run([]ort.Value{t.in}, t.outs)
+	}
+
+	var mu sync.Mutex
+	abandoned := false
+	done := make(chan error, 1)
+
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+
+		err := s.run([]ort.Value{t.in}, t.outs)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if abandoned {
+			t.destroy()
+			return
+		}
+		done <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the run may have finished while waiting for the lock
+	select {
+	case err := <-done:
+		return err
+	default:
+	}
+	abandoned = true
+
+	return fmt.Errorf("%w after %s", ErrChunkTimeout, timeout)
+}
+
+// runRaw preprocesses the images in chunks and runs them through the session, it must be called with mu held
+func (s *TaggerSession) runRaw(images []image.Image, opts RunOptions) ([][]float32, error) {
+	if opts.TTA != 0 {
+		return s.runAugmented(images, opts)
+	}
+
+	return s.runImages(images, opts)
+}
+
+// runImages is runRaw without test-time augmentation
+func (s *TaggerSession) runImages(images []image.Image, opts RunOptions) ([][]float32, error) {
+	return s.runBatches(len(images), opts, func(i int) ([]float32, error) {
+		return s.prepareImage(images[i], opts)
+	})
+}
+
+// prepareImage validates and preprocesses img, going through opts.PreprocessCache if set
+func (s *TaggerSession) prepareImage(img image.Image, opts RunOptions) ([]float32, error) {
+	if err := validateImage(img, opts); err != nil {
+		return nil, err
+	}
+
+	if opts.PreprocessCache == nil {
+		return prepareInput(img, s.targetSize, s.channelOrder, opts), nil
+	}
+
+	key := s.preprocessKey(img, opts)
+	if data, ok := opts.PreprocessCache.Get(key); ok {
+		return data, nil
+	}
+
+	data := prepareInput(img, s.targetSize, s.channelOrder, opts)
+	opts.PreprocessCache.Add(key, data)
+	return data, nil
+}
+
+// runBatches runs n inputs through the session in chunks, prepare returns the preprocessed input i.
+//
+// It must be called with mu held, prepare is called concurrently.
+// When a chunk times out the outputs of the chunks before it are returned with the error.
+func (s *TaggerSession) runBatches(
+	n int,
+	opts RunOptions,
+	prepare func(i int) ([]float32, error),
+) ([][]float32, error) {
+	raw, _, err := s.runChunks(n, opts, prepare)
+	return raw, err
+}
+
+// runChunks is runBatches also returning the embeddings of every input, nil without an embedding output
+func (s *TaggerSession) runChunks(
+	n int,
+	opts RunOptions,
+	prepare func(i int) ([]float32, error),
+) ([][]float32, [][]float32, error) {
+	if *s.destroyed {
+		return nil, nil, ErrSessionDestroyed
+	}
+
+	batch, err := s.chunkSize(n, opts.MaxBatch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := make([][]float32, 0, n)
+	var embeddings [][]float32
+	if n == 0 {
+		return raw, embeddings, nil
+	}
+
+	var tensors *chunkTensors
+	defer func() {
+		if tensors != nil {
+			tensors.destroy()
+		}
+	}()
+
+	chunks := (n + batch - 1) / batch
+	s.logger.Debug("running session", "images", n, "batch", batch, "chunks", chunks)
+	for chunkIndex := range chunks {
+		start := chunkIndex * batch
+		end := min(start+batch, n)
+
+		// fixed batch models always need a full tensor, the missing images are left as zeros
+		rows := end - start
+		if s.batchSize != -1 {
+			rows = s.batchSize
+		}
+
+		imgSize := 3 * s.targetSize * s.targetSize
+		imgData, err := prepareChunk(start, end, rows*imgSize, imgSize, prepare)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		inShape := s.chunkShape(rows)
+		outSize := int(s.output[1])
+
+		// every chunk has the same shape except the last one of dynamic batch models,
+		// so the tensors are only recreated when the shape changes
+		if tensors == nil || !slices.Equal(tensors.inShape, inShape) {
+			if tensors != nil {
+				tensors.destroy()
+				tensors = nil
+			}
+
+			tensors, err = s.newChunkTensors(inShape, rows)
+			if err != nil {
+				return nil, nil, chunkError(err, chunkIndex, start, end)
+			}
+		}
+
+		out, embedded, err := s.infer(tensors, imgData, opts.ChunkTimeout)
+		if errors.Is(err, ErrChunkTimeout) {
+			// the timed out run owns the tensors now, the completed chunks are still returned
+			tensors = nil
+			return raw, embeddings, chunkError(err, chunkIndex, start, end)
+		}
+		if err != nil {
+			return nil, nil, chunkError(err, chunkIndex, start, end)
+		}
+
+		for i := 0; i < end-start; i++ {
+			raw = append(raw, out[outSize*(i):outSize*(i+1)])
+		}
+		if s.embedding != nil {
+			embeddingSize := int(s.embedding[1])
+			for i := 0; i < end-start; i++ {
+				embeddings = append(embeddings, embedded[embeddingSize*i:embeddingSize*(i+1)])
+			}
+		}
+	}
+
+	return raw, embeddings, nil
+}
+
+// chunkShape returns the input shape of a chunk of rows images
+func (s *TaggerSession) chunkShape(rows int) ort.Shape {
+	inShape := s.input.Clone()
+	inShape[0] = int64(rows)
+	// dynamic spatial dimensions take the target size, the channels dimension is always fixed
+	for i := 1; i < len(inShape)-1; i++ {
+		if inShape[i] == -1 {
+			inShape[i] = int64(s.targetSize)
+		}
+	}
+
+	return inShape
+}
+
+// chunkError sets the chunk of err when it is a RunError
+func chunkError(err error, chunk, start, end int) error {
+	var runErr *RunError
+	if errors.As(err, &runErr) {
+		runErr.Chunk = chunk
+		runErr.Start = start
+		runErr.End = end
+	}
+
+	return err
+}
+
+// prepareChunk preprocesses the inputs from start to end in parallel, bounded by GOMAXPROCS,
+// into a buffer of size values where input i is at offset (i-start)*imgSize.
+//
+// The rest of the buffer is left as zeros, on failure the error of the lowest failing input is returned.
+func prepareChunk(
+	start, end, size, imgSize int,
+	prepare func(i int) ([]float32, error),
+) ([]float32, error) {
+	imgData := make([]float32, size)
+	errs := make([]error, end-start)
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	var wg sync.WaitGroup
+	for i := start; i < end; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			input, err := prepare(i)
+			if err != nil {
+				errs[i-start] = &ImageError{Index: i, Err: err}
+				return
+			}
+			copy(imgData[(i-start)*imgSize:(i-start+1)*imgSize], input)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return imgData, nil
+}
@@ -0,0 +1,155 @@
+package gotagger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"strings"
+)
+
+// RunJSONL tags the images in batches and writes the Predictions of every image to w as a compact JSON line,
+// in input order, as soon as its batch is done.
+//
+// The lines are encoded straight from the model output without building the Predictions maps,
+// they are the same as encoding the Predictions with encoding/json, keys included in sorted order.
+// RunOptions.Cache is not used, on error the lines of the previous batches are already written.
+func (s *TaggerSession) RunJSONL(images []image.Image, w io.Writer, opts RunOptions) error {
+	batch, err := s.chunkSize(streamBatchSize, opts.MaxBatch)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	start := 0
+	for chunk := range slices.Chunk(images, batch) {
+		if err := s.writeJSONL(bw, chunk, start, opts); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return fmt.Errorf("error while writing images %d to %d: %w", start, start+len(chunk)-1, err)
+		}
+		start += len(chunk)
+	}
+
+	return nil
+}
+
+// writeJSONL runs a chunk of images starting at index start and writes their lines to w
+func (s *TaggerSession) writeJSONL(w *bufio.Writer, images []image.Image, start int, opts RunOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.warnIgnoredOptions(opts)
+
+	runRaw := s.runRaw
+	if opts.Dedupe {
+		runRaw = s.runRawDeduped
+	}
+
+	raw, err := runRaw(images, opts)
+	if err != nil {
+		return fmt.Errorf("images %d to %d: %w", start, start+len(images)-1, err)
+	}
+
+	for i, data := range raw {
+		if err := checkFinite(data, opts.ZeroNonFinite); err != nil {
+			return &ImageError{Index: start + i, Err: err}
+		}
+
+		s.writeSelectionJSON(w, s.selectTags(data, opts), opts)
+	}
+
+	return nil
+}
+
+// writeSelectionJSON writes sel as the JSON encoding of the Predictions built from it, followed by a newline
+func (t *modelTags) writeSelectionJSON(w *bufio.Writer, sel tagSelection, opts RunOptions) {
+	general := t.scoreEntries(sel.general, sel.scores)
+	for name, score := range sel.implied {
+		general = append(general, jsonEntry{name, score})
+	}
+
+	w.WriteString(`{"General":`)
+	writeObjectJSON(w, general)
+	w.WriteString(`,"Rating":`)
+	writeObjectJSON(w, t.scoreEntries(sel.rating, sel.scores))
+	w.WriteString(`,"Character":`)
+	writeObjectJSON(w, t.scoreEntries(sel.character, sel.scores))
+	w.WriteString(`,"GeneralThresholdUsed":`)
+	writeJSON(w, sel.generalThreshold)
+	w.WriteString(`,"CharacterThresholdUsed":`)
+	writeJSON(w, sel.characterThreshold)
+	w.WriteString(`,"RawNames":`)
+
+	all := slices.Concat(sel.general, sel.rating, sel.character)
+	if !opts.RawNames || len(all) == 0 {
+		w.WriteString("null")
+	} else {
+		rawNames := make([]jsonEntry, len(all))
+		for i, index := range all {
+			rawNames[i] = jsonEntry{t.names[index], t.rawNames[index]}
+		}
+		writeObjectJSON(w, rawNames)
+	}
+
+	if opts.Sorted && len(general) != 0 {
+		scores := make(map[string]float32, len(general))
+		for _, entry := range general {
+			scores[entry.name] = entry.value.(float32)
+		}
+		w.WriteString(`,"SortedGeneral":`)
+		writeJSON(w, sortedTagScores(scores, CategoryGeneral))
+	}
+	w.WriteString("}\n")
+}
+
+// jsonEntry is a key and value of a JSON object
+type jsonEntry struct {
+	name  string
+	value any
+}
+
+// scoreEntries returns the names and scores of the tags at indexes
+func (t *modelTags) scoreEntries(indexes []int, scores []float32) []jsonEntry {
+	entries := make([]jsonEntry, len(indexes))
+	for i, index := range indexes {
+		entries[i] = jsonEntry{t.names[index], scores[index]}
+	}
+
+	return entries
+}
+
+// writeObjectJSON writes entries as a JSON object with the keys sorted like encoding/json does,
+// when names repeat the last entry wins like it would in a map
+func writeObjectJSON(w *bufio.Writer, entries []jsonEntry) {
+	slices.SortStableFunc(entries, func(a, b jsonEntry) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	w.WriteByte('{')
+	first := true
+	for i, entry := range entries {
+		if i+1 < len(entries) && entries[i+1].name == entry.name {
+			continue
+		}
+
+		if !first {
+			w.WriteByte(',')
+		}
+		first = false
+
+		writeJSON(w, entry.name)
+		w.WriteByte(':')
+		writeJSON(w, entry.value)
+	}
+	w.WriteByte('}')
+}
+
+// writeJSON writes the encoding/json encoding of a string, a float32 or TagScores, which can't fail
+func writeJSON(w *bufio.Writer, v any) {
+	b, _ := json.Marshal(v)
+	w.Write(b)
+}
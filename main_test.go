@@ -0,0 +1,55 @@
+package gotagger
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+)
+
+// ortReady is set when ONNXRUNTIME_LIB points to the ORT shared library and the environment initialized
+var ortReady bool
+
+func TestMain(m *testing.M) {
+	if lib := os.Getenv("ONNXRUNTIME_LIB"); lib != "" {
+		if err := InitRuntime(lib); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		ortReady = true
+	}
+
+	code := m.Run()
+	if ortReady {
+		ShutdownRuntime()
+	}
+	os.Exit(code)
+}
+
+// testModel returns the model and tags paths of GOTAGGER_TEST_MODEL and GOTAGGER_TEST_TAGS,
+// skipping the test when ORT or any of them is missing.
+func testModel(t *testing.T) (string, string) {
+	t.Helper()
+
+	model, tags := os.Getenv("GOTAGGER_TEST_MODEL"), os.Getenv("GOTAGGER_TEST_TAGS")
+	if !ortReady || model == "" || tags == "" {
+		t.Skip("ONNXRUNTIME_LIB, GOTAGGER_TEST_MODEL and GOTAGGER_TEST_TAGS must be set")
+	}
+
+	return model, tags
+}
+
+// destroyedSession returns a session without ORT that fails every run with ErrSessionDestroyed,
+// for the paths that don't need inference
+func destroyedSession() TaggerSession {
+	destroyed := true
+	return TaggerSession{
+		targetSize: 8,
+		batchSize:  -1,
+		logger:     slog.New(slog.DiscardHandler),
+		mu:         &sync.Mutex{},
+		pending:    &sync.WaitGroup{},
+		destroyed:  &destroyed,
+	}
+}
@@ -0,0 +1,93 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"maps"
+	"slices"
+)
+
+// RunMerged tags the images as a single set, like a post with multiple images.
+//
+// Every tag keeps its highest raw score across the images (max-pooling) and the thresholds are applied
+// to the merged scores, so a tag is kept when it passes the threshold in at least one image.
+// Ratings are max-pooled too.
+func (s *TaggerSession) RunMerged(images []image.Image, opts RunOptions) (Predictions, error) {
+	if len(images) == 0 {
+		return Predictions{}, errors.New("no images to merge")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := s.runRaw(images, opts)
+	if err != nil {
+		return Predictions{}, err
+	}
+
+	predictions, err := s.predictionsFromRaw([][]float32{maxPool(raw)}, opts)
+	if err != nil {
+		return Predictions{}, err
+	}
+
+	return predictions[0], nil
+}
+
+// maxPool returns the highest score of every tag across the raw outputs, raw must not be empty
+func maxPool(raw [][]float32) []float32 {
+	merged := slices.Clone(raw[0])
+	for _, data := range raw[1:] {
+		for i, pred := range data {
+			merged[i] = max(merged[i], pred)
+		}
+	}
+
+	return merged
+}
+
+// MergePredictions unions the tags of preds keeping the highest score of every tag, for example to combine
+// runs of the same image at several thresholds. The thresholds used are the lowest ones of preds.
+func MergePredictions(preds ...Predictions) Predictions {
+	merged := Predictions{
+		General:   map[string]float32{},
+		Rating:    map[string]float32{},
+		Character: map[string]float32{},
+	}
+
+	sorted := false
+	for i, p := range preds {
+		sorted = sorted || p.SortedGeneral != nil
+		mergeScores(merged.General, p.General)
+		mergeScores(merged.Rating, p.Rating)
+		mergeScores(merged.Character, p.Character)
+
+		if i == 0 || p.GeneralThresholdUsed < merged.GeneralThresholdUsed {
+			merged.GeneralThresholdUsed = p.GeneralThresholdUsed
+		}
+		if i == 0 || p.CharacterThresholdUsed < merged.CharacterThresholdUsed {
+			merged.CharacterThresholdUsed = p.CharacterThresholdUsed
+		}
+
+		if p.RawNames != nil {
+			if merged.RawNames == nil {
+				merged.RawNames = map[string]string{}
+			}
+			maps.Copy(merged.RawNames, p.RawNames)
+		}
+	}
+
+	if sorted {
+		merged.SortedGeneral = sortedTagScores(merged.General, CategoryGeneral)
+	}
+
+	return merged
+}
+
+// mergeScores stores in dst the scores of src that are higher or missing
+func mergeScores(dst, src map[string]float32) {
+	for name, score := range src {
+		if current, ok := dst[name]; !ok || score > current {
+			dst[name] = score
+		}
+	}
+}
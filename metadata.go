@@ -0,0 +1,81 @@
+package gotagger
+
+import (
+	"maps"
+	"strconv"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// loadMetadata reads the metadata embedded in the model, custom metadata keys are stored as is.
+//
+// Errors reading the metadata are ignored since most models don't embed any.
+func loadMetadata(modelPath string, input, output ort.Shape) map[string]string {
+	metadata := map[string]string{
+		"model_path":   modelPath,
+		"input_shape":  input.String(),
+		"output_shape": output.String(),
+	}
+
+	m, err := ort.GetModelMetadata(modelPath)
+	if err != nil {
+		return metadata
+	}
+	defer m.Destroy()
+
+	if v, err := m.GetProducerName(); err == nil && v != "" {
+		metadata["producer"] = v
+	}
+	if v, err := m.GetGraphName(); err == nil && v != "" {
+		metadata["graph"] = v
+	}
+	if v, err := m.GetDomain(); err == nil && v != "" {
+		metadata["domain"] = v
+	}
+	if v, err := m.GetDescription(); err == nil && v != "" {
+		metadata["description"] = v
+	}
+	if v, err := m.GetVersion(); err == nil {
+		metadata["version"] = strconv.FormatInt(v, 10)
+	}
+
+	keys, err := m.GetCustomMetadataMapKeys()
+	if err != nil {
+		return metadata
+	}
+	for _, key := range keys {
+		if v, ok, err := m.LookupCustomMetadataMap(key); err == nil && ok {
+			metadata[key] = v
+		}
+	}
+
+	return metadata
+}
+
+// Metadata returns a copy of the model metadata.
+//
+// It always contains "model_path", "input_shape" and "output_shape", and when the model embeds them
+// "producer", "graph", "domain", "description", "version" and any custom metadata key.
+func (s *TaggerSession) Metadata() map[string]string {
+	return maps.Clone(s.metadata)
+}
+
+// InputShape returns the input shape of the model, -1 dimensions are dynamic
+func (s *TaggerSession) InputShape() ort.Shape {
+	return s.input.Clone()
+}
+
+// OutputShape returns the output shape of the model, with every selected output concatenated
+func (s *TaggerSession) OutputShape() ort.Shape {
+	return s.output.Clone()
+}
+
+// BatchSize returns the fixed batch size of the model or -1 if it is dynamic
+func (s *TaggerSession) BatchSize() int {
+	return s.batchSize
+}
+
+// TargetSize returns the side of the square images the model is fed with
+func (s *TaggerSession) TargetSize() int {
+	return s.targetSize
+}
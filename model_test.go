@@ -0,0 +1,62 @@
+package gotagger
+
+import (
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestResolveTargetSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		shape    ort.Shape
+		override int
+		want     int
+		wantErr  bool
+	}{
+		{"fixed", ort.NewShape(1, 448, 448, 3), 0, 448, false},
+		{"fixed with the same override", ort.NewShape(1, 448, 448, 3), 448, 448, false},
+		{"fixed with another override", ort.NewShape(1, 448, 448, 3), 512, 0, true},
+		{"dynamic with override", ort.NewShape(-1, -1, -1, 3), 512, 512, false},
+		{"dynamic without override", ort.NewShape(-1, -1, -1, 3), 0, 0, true},
+		{"dynamic width only", ort.NewShape(1, 448, -1, 3), 384, 384, false},
+		{"negative override", ort.NewShape(1, 448, 448, 3), -1, 0, true},
+		{"not NHWC", ort.NewShape(1, 448, 448), 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTargetSize(tt.shape, tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBatchSize(t *testing.T) {
+	tests := []struct {
+		batch   int64
+		want    int
+		wantErr bool
+	}{
+		{-1, -1, false},
+		{1, 1, false},
+		{8, 8, false},
+		{0, 0, true},
+		{-2, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveBatchSize(ort.NewShape(tt.batch, 448, 448, 3))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("batch %d: got error %v, want error %v", tt.batch, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("batch %d: got %d, want %d", tt.batch, got, tt.want)
+		}
+	}
+}
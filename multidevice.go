@@ -0,0 +1,117 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// MultiDeviceRunner splits the images of a call across sessions of the same model on different GPUs,
+// running them concurrently.
+//
+// It needs the CUDA or DirectML provider and a device per id, sessions on the same device work
+// but gain nothing over a single session.
+type MultiDeviceRunner struct {
+	sessions []*TaggerSession
+}
+
+// NewMultiDeviceRunner creates a session of the model on every device of deviceIDs with opts,
+// only Options.DeviceID changes between them. The tags are read once and shared by every session.
+func NewMultiDeviceRunner(modelPath string, tagsPath string, deviceIDs []int, opts Options) (*MultiDeviceRunner, error) {
+	if len(deviceIDs) == 0 {
+		return nil, errors.New("a multi device runner needs at least one device")
+	}
+	if opts.Provider != ProviderCUDA && opts.Provider != ProviderDirectML {
+		return nil, fmt.Errorf("a multi device runner needs a GPU provider, got %s", opts.Provider)
+	}
+
+	runner := &MultiDeviceRunner{sessions: make([]*TaggerSession, 0, len(deviceIDs))}
+	for i, id := range deviceIDs {
+		deviceOpts := opts
+		deviceOpts.DeviceID = id
+
+		var (
+			session TaggerSession
+			err     error
+		)
+		if i == 0 {
+			session, err = NewWithOptions(modelPath, tagsPath, deviceOpts)
+		} else {
+			session, err = runner.sessions[0].CloneWithOptions(deviceOpts)
+		}
+		if err != nil {
+			runner.Destroy()
+			return nil, fmt.Errorf("error while creating session for device %d: %w", id, err)
+		}
+
+		runner.sessions = append(runner.sessions, &session)
+	}
+
+	return runner, nil
+}
+
+// Run splits images in chunks that are sent round-robin to the devices, the predictions are in input order.
+//
+// Chunks are RunOptions.MaxBatch images, or the fixed batch size of the model, and without either the images
+// are split evenly across the devices.
+func (r *MultiDeviceRunner) Run(images []image.Image, opts RunOptions) ([]Predictions, error) {
+	devices := len(r.sessions)
+	size := (len(images) + devices - 1) / devices
+	if opts.MaxBatch > 0 || r.sessions[0].batchSize != -1 {
+		var err error
+		size, err = r.sessions[0].chunkSize(len(images), opts.MaxBatch)
+		if err != nil {
+			return nil, err
+		}
+	}
+	size = max(size, 1)
+
+	predictions := make([]Predictions, len(images))
+	chunks := (len(images) + size - 1) / size
+	errs := make([]error, chunks)
+
+	var wg sync.WaitGroup
+	for d, session := range r.sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for c := d; c < chunks; c += devices {
+				start := c * size
+				end := min(start+size, len(images))
+
+				out, err := session.RunWithOptions(images[start:end], opts)
+				if err != nil {
+					errs[c] = fmt.Errorf("session %d, images %d to %d: %w", d, start, end-1, err)
+					return
+				}
+				copy(predictions[start:end], out)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return predictions, nil
+}
+
+// Size returns the amount of devices of the runner
+func (r *MultiDeviceRunner) Size() int {
+	return len(r.sessions)
+}
+
+// Destroy destroys the session of every device, it must not be called while Run is in progress
+func (r *MultiDeviceRunner) Destroy() error {
+	var errs []error
+	for _, session := range r.sessions {
+		errs = append(errs, session.Destroy())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package gotagger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// npyMagic starts every .npy file, followed by the format version 1.0
+const npyMagic = "\x93NUMPY\x01\x00"
+
+// WriteNPY writes the raw outputs returned by RunRaw to w as a [images, tags] little-endian float32 matrix
+// in the .npy format (version 1.0, C order), so it can be loaded with numpy.load.
+// Every output must have the same length, write the tag names with TaggerSession.WriteNames to label the columns.
+func WriteNPY(w io.Writer, raw [][]float32) error {
+	cols := 0
+	if len(raw) != 0 {
+		cols = len(raw[0])
+	}
+	for i, row := range raw {
+		if len(row) != cols {
+			return fmt.Errorf("output %d has %d values, expected %d", i, len(row), cols)
+		}
+	}
+
+	header := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", len(raw), cols)
+	// the magic, the header length and the header end with a newline on a multiple of 64 bytes
+	padding := 64 - (len(npyMagic)+2+len(header)+1)%64
+	header += strings.Repeat(" ", padding%64) + "\n"
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(npyMagic)
+	binary.Write(bw, binary.LittleEndian, uint16(len(header)))
+	bw.WriteString(header)
+
+	buf := make([]byte, 4)
+	for _, row := range raw {
+		for _, value := range row {
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(value))
+			bw.Write(buf)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error while writing npy: %w", err)
+	}
+
+	return nil
+}
+
+// WriteNames writes the tag names of the session to w, one per line in model output order,
+// which are the columns of the matrix written by WriteNPY
+func (s *TaggerSession) WriteNames(w io.Writer) error {
+	if _, err := io.WriteString(w, strings.Join(s.Names(), "\n")+"\n"); err != nil {
+		return fmt.Errorf("error while writing names: %w", err)
+	}
+
+	return nil
+}
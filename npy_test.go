@@ -0,0 +1,58 @@
+package gotagger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteNPY(t *testing.T) {
+	raw := [][]float32{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}}
+
+	var buf bytes.Buffer
+	if err := WriteNPY(&buf, raw); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("\x93NUMPY")) {
+		t.Fatalf("missing magic: %q", out[:6])
+	}
+	if out[6] != 1 || out[7] != 0 {
+		t.Fatalf("version: got %d.%d, want 1.0", out[6], out[7])
+	}
+
+	headerLen := int(binary.LittleEndian.Uint16(out[8:10]))
+	dataStart := 10 + headerLen
+	if dataStart%64 != 0 {
+		t.Errorf("data starts at %d, not aligned to 64 bytes", dataStart)
+	}
+
+	header := string(out[10:dataStart])
+	for _, want := range []string{"'descr': '<f4'", "'fortran_order': False", "'shape': (2, 3)"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("header %q has no %s", header, want)
+		}
+	}
+	if !strings.HasSuffix(header, "\n") {
+		t.Errorf("header %q doesn't end with a newline", header)
+	}
+
+	payload := out[dataStart:]
+	if len(payload) != 2*3*4 {
+		t.Fatalf("payload: got %d bytes, want %d", len(payload), 2*3*4)
+	}
+	for i, want := range []float32{0.1, 0.2, 0.3, 0.4, 0.5, 0.6} {
+		if got := math.Float32frombits(binary.LittleEndian.Uint32(payload[i*4:])); got != want {
+			t.Errorf("value %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestWriteNPYMismatchedRows(t *testing.T) {
+	if err := WriteNPY(&bytes.Buffer{}, [][]float32{{0.1, 0.2}, {0.3}}); err == nil {
+		t.Fatal("expected an error for rows of different lengths")
+	}
+}
@@ -0,0 +1,260 @@
+package gotagger
+
+import (
+	"image"
+	"image/color"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+// RunOptions are the settings used by RunWithOptions and the helpers built on top of it
+type RunOptions struct {
+	// GeneralThreshold is the minimum prediction for a general tag to be in the output
+	GeneralThreshold float32
+	// CharacterThreshold is the minimum prediction for a character tag to be in the output
+	CharacterThreshold float32
+	// ThresholdEpsilon relaxes the general, character and rating thresholds: with a positive epsilon a tag is kept
+	// when its score is at least the threshold minus epsilon, so tags exactly at the threshold and ones below it
+	// by floating point noise are kept. 0 keeps the strict comparison, a tag must score above the threshold
+	ThresholdEpsilon float32
+	// MaxGeneralTags keeps only the best general tags passing the threshold, 0 keeps all of them
+	MaxGeneralTags int
+	// MergeSimilarTags merges general tags within this Levenshtein distance of a tag with a higher score,
+	// like "smile" and "smiling", keeping only the higher scoring one. 0 disables it.
+	// Tags shorter than 5 runes are never merged since they can differ by a single letter and mean something else
+	MergeSimilarTags int
+	// RatingThreshold drops the ratings with a score not above it, 0 keeps every rating
+	RatingThreshold float32
+	// GeneralMCut computes the general threshold with mcut instead of using GeneralThreshold
+	GeneralMCut bool
+	// RelativeThreshold computes the general threshold of every image as this fraction of its best general tag score,
+	// 0.5 keeps the tags scoring above half of the best one. 0 disables it and GeneralMCut takes precedence over it
+	RelativeThreshold float32
+	// PerTagThresholds overrides the threshold of the general and character tags it has, keyed by their name
+	// as found in Predictions. The other tags use the threshold of their category, see LoadThresholds
+	PerTagThresholds map[string]float32
+	// CharacterMCut computes the character threshold with mcut instead of using CharacterThreshold
+	CharacterMCut bool
+	// MaxBatch caps how many images go into a single inference call, 0 means no cap.
+	//
+	// For dynamic batch models (batch dimension -1) every image goes into one call unless this is set.
+	// For fixed batch models it must not exceed the model batch size, smaller chunks are padded
+	// up to the fixed batch size with empty images.
+	MaxBatch int
+	// ChunkTimeout fails a chunk with ErrChunkTimeout when its inference call takes longer, 0 means no timeout.
+	//
+	// The predictions of the chunks completed before it are still returned along with the error.
+	// ORT calls can't be canceled, so the timed out call keeps running in the background (holding
+	// its goroutine and tensors) until ORT returns, but the caller is unblocked.
+	ChunkTimeout time.Duration
+	// RejectGrayscale makes the run fail with ErrGrayscale when an image is *image.Gray or *image.Gray16,
+	// otherwise grayscale images are upconverted to RGB with the same value in every channel.
+	// RunEach only fails the grayscale images and tags the others
+	RejectGrayscale bool
+	// Preprocess is how images are made square, defaults to PreprocessPad
+	Preprocess PreprocessMode
+	// Padding is how the square canvas of PreprocessPad and PreprocessFit is filled, defaults to PadWhite
+	Padding PadMode
+	// PadColor is the padding color used with PadColor, it is not part of SessionConfig
+	PadColor color.Color `json:"-"`
+	// MinResolution rejects the images whose smaller side is below it with ErrLowResolution, 0 accepts any size.
+	// RunEach skips the rejected images and tags the others
+	MinResolution int
+	// MaxInputDimension downscales images whose largest side is bigger than it before padding,
+	// bounding the memory used by huge images, 0 means no limit
+	MaxInputDimension int
+	// Resample is the filter used when resizing images, defaults to ResampleLanczos
+	Resample Resample
+	// TTA averages the scores of every image with the ones of its augmented variants before the thresholds,
+	// each variant costs a full inference so TTAHFlip doubles the compute and all of them multiply it by 8
+	TTA TTA
+	// ApplySigmoid maps the model outputs through a sigmoid before the thresholds,
+	// for models that output logits instead of probabilities
+	ApplySigmoid bool
+	// Temperature softens (above 1) or sharpens (below 1) the scores before the thresholds, 0 means 1 which
+	// leaves them unchanged. With ApplySigmoid the logits are divided by it before the sigmoid, otherwise
+	// the probabilities are turned back into logits, divided and mapped through the sigmoid again,
+	// which is the power transform p^(1/T) / (p^(1/T) + (1-p)^(1/T)), so both give the same scores
+	Temperature float32
+	// ZeroNonFinite replaces NaN and Inf model outputs with 0 instead of failing with ErrNonFinite
+	ZeroNonFinite bool
+	// MinTagCount drops general and character tags with a post count lower than it,
+	// it does nothing when the tags dataset has no count column
+	MinTagCount int
+	// Cache skips the inference of images whose predictions are already stored, keyed by ImageHash.
+	//
+	// The key only depends on the image, use a different cache for each set of options.
+	Cache Cache `json:"-"`
+	// Dedupe runs identical images of a call only once, comparing them by ImageHash,
+	// which saves inference on batches with repeats at the cost of hashing every image
+	Dedupe bool
+	// PreprocessCache stores the preprocessed images keyed by ImageHash and the preprocessing settings,
+	// so running the same images again, for example with other thresholds, skips their preprocessing
+	PreprocessCache *PreprocessCache `json:"-"`
+	// RoundDecimals rounds the scores of the predictions half up to this many decimals, 0 keeps them as is.
+	// The thresholds are applied before rounding
+	RoundDecimals int
+	// Implications adds the tags implied by the general and character tags of the predictions to the general tags,
+	// like Danbooru does with cat ears implying animal ears. It maps a tag to the tags it implies, all named as
+	// in Predictions, and is followed transitively. Implied tags are not in RawNames
+	Implications map[string][]string
+	// ImplicationDiscount multiplies the score of the implying tag to get the one of the implied tag,
+	// once per implication step. It must be between 0 and 1, 0 keeps the same score
+	ImplicationDiscount float32
+	// Sorted fills Predictions.SortedGeneral with the general tags sorted by descending score
+	Sorted bool
+	// RawNames fills Predictions.RawNames with the original name of every tag
+	RawNames bool
+	// Extensions are the file extensions tagged by RunDir, with or without the leading dot and matched case-insensitively.
+	// Defaults to jpg, jpeg and png, other formats also need their decoder imported (like golang.org/x/image/webp)
+	Extensions []string
+	// ROIs crops the images of RunPaths and RunDir to a region before tagging them, keyed by the path as given
+	// to RunPaths or found by RunDir. Images without a region are tagged whole, images whose region is empty
+	// or outside of their bounds fail with a per image error
+	ROIs map[string]image.Rectangle
+	// FetchTimeout is how long RunURL waits for the download, defaults to 30 seconds
+	FetchTimeout time.Duration
+	// MaxDownloadSize is the largest image RunURL downloads in bytes, defaults to 32 MiB
+	MaxDownloadSize int64
+	// HTTPClient is the client RunURL downloads with, defaults to http.DefaultClient
+	HTTPClient *http.Client `json:"-"`
+	// FlushInterval is how long RunStream waits for a batch to fill before running it anyway,
+	// 0 waits until the batch is full or the input is closed
+	FlushInterval time.Duration
+	// MergeRatingIntoGeneral copies the most likely rating into the general tags, Rating is kept as is
+	MergeRatingIntoGeneral bool
+	// MergeRatingThreshold makes MergeRatingIntoGeneral copy every rating above it instead of only the best one
+	MergeRatingThreshold float32
+}
+
+// DefaultRunOptions returns the RunOptions with the default thresholds and mcut disabled
+func DefaultRunOptions() RunOptions {
+	return RunOptions{
+		GeneralThreshold:   DefaultGeneralThreshold,
+		CharacterThreshold: DefaultCharacterThreshold,
+	}
+}
+
+// Options are the settings used by NewWithOptions when creating a session
+type Options struct {
+	// Float16 must be set for models with float16 input and output tensors.
+	//
+	// Images are still preprocessed as float32 and converted to float16 before inference,
+	// the output is converted back to float32 so predictions behave the same.
+	Float16 bool
+	// Float16Output must be set for models with a float32 input and float16 outputs,
+	// the outputs are converted to float32 before the thresholds. It is implied by Float16
+	Float16Output bool
+	// TargetSize is the image size fed to the model, it is only required when the model
+	// has a dynamic height/width (-1), otherwise it is detected from the model input.
+	TargetSize int
+	// InputName is the model input fed with the images, defaults to the first input
+	InputName string
+	// OutputName is the model output holding the tags, it is a shorthand for Outputs with a single output
+	OutputName string
+	// Outputs are the names of the model outputs holding the tags, required for models with more than one output.
+	//
+	// The outputs are concatenated in this order so they line up with the tags dataset,
+	// for example a rating head followed by a tags head.
+	Outputs []string
+	// Delimiter is the field separator of the tags dataset, defaults to a comma, use '\t' for TSV files
+	Delimiter rune
+	// RatingsPath is a CSV with the name and index columns listing the rating labels and their output index,
+	// for models whose tags dataset has no ratings. It replaces the ratings of the tags dataset
+	RatingsPath string
+	// CategoryMap maps the values of the category column of the tags dataset to their Category,
+	// defaults to the Danbooru codes used by the WD models (0 general, 1 artist, 3 copyright, 4 character,
+	// 5 meta and 9 rating). Datasets with values missing from it fail to load
+	CategoryMap map[string]Category
+	// DisplayColumn is a column of the tags dataset, like a localized name, used as the tag names
+	// in the predictions instead of the name column. Tags with an empty value and datasets without
+	// the column fall back to the name column, which is still what RawNames holds
+	DisplayColumn string
+	// StrictCategories makes NewWithOptions and ReloadTags fail with ErrMissingCategory when the tags dataset
+	// has no general, character or rating tags, which usually means a malformed or mismatched dataset.
+	// Otherwise it is logged
+	StrictCategories bool
+	// Kaomojis are the tags of the name column whose underscores are not replaced with spaces,
+	// defaults to DefaultKaomojis. It replaces the built-in set, extend a copy of DefaultKaomojis to keep it
+	Kaomojis map[string]struct{}
+	// ChannelOrder is the channel order of the model input, by default it is detected from the model metadata
+	// and input name and falls back to BGR
+	ChannelOrder ChannelOrder
+	// EmbeddingOutput is the name of a model output holding a feature vector of the image, like the
+	// penultimate layer, returned by RunWithEmbeddings. It must have the [batch, features] shape
+	EmbeddingOutput string
+	// Provider is the execution provider of the session, defaults to ProviderCPU
+	Provider Provider
+	// DeviceID is the GPU used by the CUDA and DirectML providers
+	DeviceID int
+	// GPUMemLimit caps the bytes the CUDA arena can allocate, 0 means unlimited (the ORT default)
+	GPUMemLimit uint64
+	// ArenaExtendStrategy is how the CUDA arena grows, "kNextPowerOfTwo" (the ORT default) or "kSameAsRequested"
+	ArenaExtendStrategy string
+	// Logger receives debug and warning events of the session, nothing is logged when it is nil
+	Logger *slog.Logger
+	// RunDefaults are the options used by RunDefault, defaults to DefaultRunOptions.
+	// They can be changed later with TaggerSession.SetDefaults
+	RunDefaults *RunOptions
+}
+
+// PreprocessMode is how images are made square before being resized to the model size
+type PreprocessMode int
+
+const (
+	// PreprocessPad pads the image into a square of its largest side, keeping the whole image
+	PreprocessPad PreprocessMode = iota
+	// PreprocessCenterCrop crops the largest centered square of the image, cutting the edges of the longest side
+	PreprocessCenterCrop
+	// PreprocessFit resizes the image to fit in the model size keeping its aspect ratio, then pads the remainder.
+	//
+	// PreprocessPad pads at the source resolution and resizes the padded square, so the padding is resampled
+	// along with the image, here only the image is resampled and the padding stays sharp.
+	// The image is centered the same way whichever side is longer
+	PreprocessFit
+)
+
+// PadMode is how the padding around the image is filled by PreprocessPad and PreprocessFit
+type PadMode int
+
+const (
+	// PadWhite fills the padding with white
+	PadWhite PadMode = iota
+	// PadEdgeReplicate extends the border pixels of the image outwards, avoiding a hard edge
+	PadEdgeReplicate
+	// PadBlack fills the padding with black
+	PadBlack
+	// PadColor fills the padding with RunOptions.PadColor
+	PadColor
+	// PadAverageColor fills the padding with the average color of the image, ignoring transparent pixels
+	PadAverageColor
+)
+
+// Resample is the filter used to resize images
+type Resample int
+
+const (
+	// ResampleLanczos is a high quality filter, the default
+	ResampleLanczos Resample = iota
+	// ResampleNearest copies the nearest pixel without interpolating, keeping the hard edges of pixel art
+	ResampleNearest
+	// ResampleLinear is a bilinear filter
+	ResampleLinear
+	// ResampleCatmullRom is a sharp cubic filter
+	ResampleCatmullRom
+)
+
+func (r Resample) filter() imaging.ResampleFilter {
+	switch r {
+	case ResampleNearest:
+		return imaging.NearestNeighbor
+	case ResampleLinear:
+		return imaging.Linear
+	case ResampleCatmullRom:
+		return imaging.CatmullRom
+	}
+	return imaging.Lanczos
+}
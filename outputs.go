@@ -0,0 +1,121 @@
+package gotagger
+
+import (
+	"fmt"
+	"strings"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// resolveInput returns the input fed with the images, the first one unless name is set
+func resolveInput(inputs []ort.InputOutputInfo, name string) (ort.InputOutputInfo, error) {
+	if name == "" {
+		return inputs[0], nil
+	}
+
+	available := make([]string, len(inputs))
+	for i, input := range inputs {
+		if input.Name == name {
+			return input, nil
+		}
+		available[i] = input.Name
+	}
+
+	return ort.InputOutputInfo{}, fmt.Errorf(
+		"model has no input named %s, available inputs are: %s",
+		name,
+		strings.Join(available, ", "),
+	)
+}
+
+// resolveOutputs returns the outputs read by the session.
+//
+// Models with a single output use it, models with more outputs need the names of the ones to read,
+// their values are concatenated in order to match the tags dataset.
+func resolveOutputs(outputs []ort.InputOutputInfo, names []string) ([]ort.InputOutputInfo, error) {
+	available := make([]string, len(outputs))
+	for i, output := range outputs {
+		available[i] = output.Name
+	}
+
+	var resolved []ort.InputOutputInfo
+	if len(names) == 0 {
+		if len(outputs) != 1 {
+			return nil, fmt.Errorf(
+				"model has %d outputs (%s), set Options.Outputs to the ones holding the tags",
+				len(outputs),
+				strings.Join(available, ", "),
+			)
+		}
+		resolved = outputs
+	}
+
+	for _, name := range names {
+		found := false
+		for _, output := range outputs {
+			if output.Name == name {
+				resolved = append(resolved, output)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf(
+				"model has no output named %s, available outputs are: %s",
+				name,
+				strings.Join(available, ", "),
+			)
+		}
+	}
+
+	for _, output := range resolved {
+		if len(output.Dimensions) != 2 {
+			return nil, fmt.Errorf(
+				"unsupported shape %s of output %s, expected [batch, tags]",
+				output.Dimensions,
+				output.Name,
+			)
+		}
+	}
+
+	return resolved, nil
+}
+
+// combinedShape returns the shape of the concatenation of heads, [batch, total tags]
+func combinedShape(heads []ort.Shape) ort.Shape {
+	var total int64
+	for _, head := range heads {
+		total += head[1]
+	}
+
+	return ort.NewShape(heads[0][0], total)
+}
+
+// headShapes returns the output shapes for a batch of rows images
+func (s *TaggerSession) headShapes(rows int) []ort.Shape {
+	shapes := make([]ort.Shape, len(s.heads))
+	for i, head := range s.heads {
+		shapes[i] = head.Clone()
+		shapes[i][0] = int64(rows)
+	}
+
+	return shapes
+}
+
+// mergeHeads concatenates the output of every head per image
+func mergeHeads(outs [][]float32, shapes []ort.Shape) []float32 {
+	if len(outs) == 1 {
+		return outs[0]
+	}
+
+	rows := int(shapes[0][0])
+	merged := make([]float32, 0, int(combinedShape(shapes).FlattenedSize()))
+	for i := 0; i < rows; i++ {
+		for h, out := range outs {
+			k := int(shapes[h][1])
+			merged = append(merged, out[i*k:(i+1)*k]...)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,61 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// SessionPool holds identical sessions so multiple goroutines can run inference at the same time,
+// a single TaggerSession runs one call at a time
+type SessionPool struct {
+	sessions []*TaggerSession
+	idle     chan *TaggerSession
+}
+
+// NewSessionPool creates a pool of n sessions of the same model and tags, see NewWithOptions
+func NewSessionPool(modelPath string, tagsPath string, n int, opts Options) (*SessionPool, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid pool size %d, it must be at least 1", n)
+	}
+
+	pool := &SessionPool{
+		sessions: make([]*TaggerSession, 0, n),
+		idle:     make(chan *TaggerSession, n),
+	}
+	for i := 0; i < n; i++ {
+		session, err := NewWithOptions(modelPath, tagsPath, opts)
+		if err != nil {
+			pool.Destroy()
+			return nil, fmt.Errorf("error while creating session %d of the pool: %w", i, err)
+		}
+
+		pool.sessions = append(pool.sessions, &session)
+		pool.idle <- &session
+	}
+
+	return pool, nil
+}
+
+// Run waits for an idle session and runs the images with it, see TaggerSession.RunWithOptions
+func (p *SessionPool) Run(images []image.Image, opts RunOptions) ([]Predictions, error) {
+	session := <-p.idle
+	defer func() { p.idle <- session }()
+
+	return session.RunWithOptions(images, opts)
+}
+
+// Size returns the amount of sessions in the pool
+func (p *SessionPool) Size() int {
+	return len(p.sessions)
+}
+
+// Destroy destroys every session of the pool, it must not be called while Run is in progress
+func (p *SessionPool) Destroy() error {
+	var errs []error
+	for _, session := range p.sessions {
+		errs = append(errs, session.Destroy())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,374 @@
+package gotagger
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+func mcutThreshold(probs []float32) float32 {
+	if len(probs) < 2 {
+		if len(probs) == 0 {
+			return 0
+		}
+		return probs[0]
+	}
+
+	sortedProbs := make([]float32, len(probs))
+	copy(sortedProbs, probs)
+	slices.SortFunc(sortedProbs, func(a, b float32) int {
+		if a > b {
+			return -1
+		} else if a < b {
+			return 1
+		}
+		return 0
+	})
+	maxDiff := float32(0)
+	maxIndex := 0
+	for i := 0; i < len(sortedProbs)-1; i++ {
+		diff := sortedProbs[i] - sortedProbs[i+1]
+		if diff > maxDiff {
+			maxDiff = diff
+			maxIndex = i
+		}
+	}
+	return (sortedProbs[maxIndex] + sortedProbs[maxIndex+1]) / 2
+}
+
+// sigmoid returns a copy of the logits in data mapped to probabilities
+func sigmoid(data []float32) []float32 {
+	probs := make([]float32, len(data))
+	for i, logit := range data {
+		probs[i] = float32(1 / (1 + math.Exp(-float64(logit))))
+	}
+
+	return probs
+}
+
+// scaleScores returns the scores of a raw output, mapped through the sigmoid and the temperature of opts when set.
+// data is returned as is when there is nothing to apply
+func scaleScores(data []float32, opts RunOptions) []float32 {
+	temperature := float64(opts.Temperature)
+	if temperature <= 0 {
+		temperature = 1
+	}
+	if temperature == 1 {
+		if opts.ApplySigmoid {
+			return sigmoid(data)
+		}
+		return data
+	}
+
+	scores := make([]float32, len(data))
+	for i, value := range data {
+		logit := float64(value)
+		if !opts.ApplySigmoid {
+			// probabilities are turned back into the logits the model applied its sigmoid to,
+			// clamped first since scores slightly outside of [0, 1] would give NaN
+			p := min(max(logit, 0), 1)
+			logit = math.Log(p / (1 - p))
+		}
+		scores[i] = float32(1 / (1 + math.Exp(-logit/temperature)))
+	}
+
+	return scores
+}
+
+// Postprocess applies the thresholds and filters of opts to a raw output returned by RunRaw,
+// it is what RunWithOptions does after inference
+func (s *TaggerSession) Postprocess(data []float32, opts RunOptions) (Predictions, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	predictions, err := s.predictionsFromRaw([][]float32{slices.Clone(data)}, opts)
+	if err != nil {
+		return Predictions{}, err
+	}
+
+	return predictions[0], nil
+}
+
+// predictionsFromRaw applies the thresholds to every raw output, it must be called with mu held
+func (s *TaggerSession) predictionsFromRaw(raw [][]float32, opts RunOptions) ([]Predictions, error) {
+	predictions, errs := s.predictionsEach(raw, opts)
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	return predictions, nil
+}
+
+// predictionsEach is predictionsFromRaw failing only the outputs that are not finite, errs[i] is their ImageError
+func (s *TaggerSession) predictionsEach(raw [][]float32, opts RunOptions) ([]Predictions, []error) {
+	s.warnIgnoredOptions(opts)
+
+	predictions := make([]Predictions, len(raw))
+	errs := make([]error, len(raw))
+	for i, data := range raw {
+		if err := checkFinite(data, opts.ZeroNonFinite); err != nil {
+			errs[i] = &ImageError{Index: i, Err: err}
+			continue
+		}
+
+		predictions[i] = s.buildPredictions(data, opts)
+		if opts.CharacterMCut && predictions[i].CharacterThresholdUsed == characterMCutFloor {
+			s.logger.Debug("character mcut threshold clamped", "image", i, "threshold", characterMCutFloor)
+		}
+	}
+
+	return predictions, errs
+}
+
+// warnIgnoredOptions logs the options of opts that have no effect with the tags of the session
+func (s *TaggerSession) warnIgnoredOptions(opts RunOptions) {
+	if opts.MinTagCount > 0 && s.counts == nil {
+		s.logger.Warn("MinTagCount is ignored, the tags dataset has no count column")
+	}
+
+	if len(opts.PerTagThresholds) != 0 {
+		known := make(map[string]struct{}, len(s.names))
+		for _, name := range s.names {
+			known[name] = struct{}{}
+		}
+		for name := range opts.PerTagThresholds {
+			if _, ok := known[name]; !ok {
+				s.logger.Warn("PerTagThresholds has an unknown tag, it is ignored", "tag", name)
+			}
+		}
+	}
+}
+
+// tagSelection are the tags of a raw output kept by the thresholds, as indexes of the tags dataset
+type tagSelection struct {
+	// scores are the scores of every tag, after the sigmoid and the temperature if enabled
+	scores    []float32
+	general   []int
+	character []int
+	rating    []int
+	// implied are the general tags added by RunOptions.Implications, they may not be in the tags dataset
+	implied            map[string]float32
+	generalThreshold   float32
+	characterThreshold float32
+}
+
+// buildPredictions applies the thresholds of opts to the raw output of a single image.
+//
+// It only depends on the tags so it can be exercised with synthetic outputs, without ORT or a model.
+func (t *modelTags) buildPredictions(data []float32, opts RunOptions) Predictions {
+	sel := t.selectTags(data, opts)
+
+	p := Predictions{
+		General:                make(map[string]float32, len(sel.general)),
+		Rating:                 make(map[string]float32, len(sel.rating)),
+		Character:              make(map[string]float32, len(sel.character)),
+		GeneralThresholdUsed:   sel.generalThreshold,
+		CharacterThresholdUsed: sel.characterThreshold,
+	}
+	if opts.RawNames {
+		p.RawNames = map[string]string{}
+	}
+
+	for _, group := range []struct {
+		indexes []int
+		scores  map[string]float32
+	}{
+		{sel.general, p.General},
+		{sel.rating, p.Rating},
+		{sel.character, p.Character},
+	} {
+		for _, index := range group.indexes {
+			name := t.names[index]
+			group.scores[name] = sel.scores[index]
+			if opts.RawNames {
+				p.RawNames[name] = t.rawNames[index]
+			}
+		}
+	}
+
+	maps.Copy(p.General, sel.implied)
+
+	if opts.Sorted && len(p.General) != 0 {
+		p.SortedGeneral = sortedTagScores(p.General, CategoryGeneral)
+	}
+
+	if len(p.RawNames) == 0 {
+		p.RawNames = nil
+	}
+
+	return p
+}
+
+// selectTags returns the tags of the raw output of a single image kept by the thresholds and filters of opts
+func (t *modelTags) selectTags(data []float32, opts RunOptions) tagSelection {
+	data = scaleScores(data, opts)
+
+	sel := tagSelection{
+		scores:             data,
+		generalThreshold:   opts.GeneralThreshold,
+		characterThreshold: opts.CharacterThreshold,
+	}
+
+	if opts.GeneralMCut {
+		var generalProbs []float32
+		for _, index := range t.generalIndexes {
+			if index < len(data) {
+				generalProbs = append(generalProbs, data[index])
+			}
+		}
+		sel.generalThreshold = mcutThreshold(generalProbs)
+	} else if opts.RelativeThreshold > 0 {
+		best := float32(0)
+		for _, index := range t.generalIndexes {
+			if index < len(data) {
+				best = max(best, data[index])
+			}
+		}
+		sel.generalThreshold = best * opts.RelativeThreshold
+	}
+
+	if opts.CharacterMCut {
+		var characterProbs []float32
+		for _, index := range t.characterIndexes {
+			if index < len(data) {
+				characterProbs = append(characterProbs, data[index])
+			}
+		}
+		sel.characterThreshold = max(mcutThreshold(characterProbs), characterMCutFloor)
+	}
+
+	// keep returns whether the tag at index passes threshold, or its own threshold if it has one
+	keep := func(index int, threshold float32) bool {
+		if index >= len(data) {
+			return false
+		}
+		if opts.MinTagCount > 0 && t.counts != nil && t.counts[index] < opts.MinTagCount {
+			return false
+		}
+		if own, ok := opts.PerTagThresholds[t.names[index]]; ok {
+			threshold = own
+		}
+
+		return passes(data[index], threshold, opts.ThresholdEpsilon)
+	}
+
+	for _, index := range t.ratingIndexes {
+		if index >= len(data) {
+			continue
+		}
+		if opts.RatingThreshold == 0 || passes(data[index], opts.RatingThreshold, opts.ThresholdEpsilon) {
+			sel.rating = append(sel.rating, index)
+		}
+	}
+	for _, index := range t.generalIndexes {
+		if keep(index, sel.generalThreshold) {
+			sel.general = append(sel.general, index)
+		}
+	}
+	for _, index := range t.characterIndexes {
+		if keep(index, sel.characterThreshold) {
+			sel.character = append(sel.character, index)
+		}
+	}
+
+	if opts.MergeSimilarTags > 0 {
+		sel.general = t.mergeSimilar(sel.general, data, opts.MergeSimilarTags)
+	}
+
+	if opts.MaxGeneralTags > 0 && len(sel.general) > opts.MaxGeneralTags {
+		slices.SortFunc(sel.general, func(a, b int) int {
+			return compareTags(t.names[a], data[a], t.names[b], data[b])
+		})
+		sel.general = sel.general[:opts.MaxGeneralTags]
+	}
+
+	if opts.MergeRatingIntoGeneral && len(sel.rating) != 0 {
+		if opts.MergeRatingThreshold > 0 {
+			for _, index := range sel.rating {
+				if data[index] > opts.MergeRatingThreshold {
+					sel.general = append(sel.general, index)
+				}
+			}
+		} else {
+			best := slices.MinFunc(sel.rating, func(a, b int) int {
+				return compareTags(t.names[a], data[a], t.names[b], data[b])
+			})
+			sel.general = append(sel.general, best)
+		}
+	}
+
+	if opts.RoundDecimals > 0 {
+		sel.scores = roundScores(data, opts.RoundDecimals)
+	}
+
+	if len(opts.Implications) != 0 {
+		sel.implied = t.impliedTags(sel, opts)
+	}
+
+	return sel
+}
+
+// passes returns whether score is above threshold, or at least threshold minus epsilon when epsilon is positive
+func passes(score, threshold, epsilon float32) bool {
+	if epsilon > 0 {
+		return score >= threshold-epsilon
+	}
+
+	return score > threshold
+}
+
+// impliedTags returns the tags implied by the general and character tags of sel that it doesn't have yet,
+// following the implications transitively. Each one gets the highest score of the tags implying it
+// multiplied by the discount once per step.
+func (t *modelTags) impliedTags(sel tagSelection, opts RunOptions) map[string]float32 {
+	// scores never grow along an implication chain so cycles stop once no score improves
+	discount := opts.ImplicationDiscount
+	if discount <= 0 || discount > 1 {
+		discount = 1
+	}
+
+	present := map[string]struct{}{}
+	type source struct {
+		name  string
+		score float32
+	}
+	var queue []source
+	for _, index := range slices.Concat(sel.general, sel.character) {
+		present[t.names[index]] = struct{}{}
+		queue = append(queue, source{t.names[index], sel.scores[index]})
+	}
+
+	implied := map[string]float32{}
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, parent := range opts.Implications[current.name] {
+			if _, ok := present[parent]; ok {
+				continue
+			}
+
+			score := current.score * discount
+			if opts.RoundDecimals > 0 {
+				score = roundScores([]float32{score}, opts.RoundDecimals)[0]
+			}
+			if score > implied[parent] {
+				implied[parent] = score
+				queue = append(queue, source{parent, score})
+			}
+		}
+	}
+
+	return implied
+}
+
+// roundScores returns a copy of scores rounded half up to decimals digits
+func roundScores(scores []float32, decimals int) []float32 {
+	scale := math.Pow10(decimals)
+	rounded := make([]float32, len(scores))
+	for i, score := range scores {
+		rounded[i] = float32(math.Floor(float64(score)*scale+0.5) / scale)
+	}
+
+	return rounded
+}
@@ -0,0 +1,295 @@
+package gotagger
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"testing"
+)
+
+// testTags returns tags with the ratings general, sensitive, questionable and explicit at 0-3,
+// the general tags at 4-8 and the character tags at 9-11
+func testTags() *modelTags {
+	names := []string{
+		"general", "sensitive", "questionable", "explicit",
+		"long hair", "smile", "cat ears", "animal ears", "hat",
+		"hatsune miku", "megurine luka", "kagamine rin",
+	}
+
+	return &modelTags{
+		names:            names,
+		rawNames:         names,
+		ratingIndexes:    []int{0, 1, 2, 3},
+		generalIndexes:   []int{4, 5, 6, 7, 8},
+		characterIndexes: []int{9, 10, 11},
+	}
+}
+
+func TestBuildPredictions(t *testing.T) {
+	data := []float32{0.7, 0.2, 0.05, 0.01, 0.9, 0.6, 0.4, 0.3, 0.1, 0.95, 0.5, 0.2}
+
+	tests := []struct {
+		name               string
+		data               []float32
+		opts               RunOptions
+		general, character []string
+		generalThreshold   float32
+		characterThreshold float32
+	}{
+		{
+			name:               "thresholds",
+			opts:               RunOptions{GeneralThreshold: 0.35, CharacterThreshold: 0.85},
+			general:            []string{"long hair", "smile", "cat ears"},
+			character:          []string{"hatsune miku"},
+			generalThreshold:   0.35,
+			characterThreshold: 0.85,
+		},
+		{
+			// the biggest gap of the general scores is 0.9-0.6
+			name:               "general mcut",
+			opts:               RunOptions{GeneralMCut: true, CharacterThreshold: 0.85},
+			general:            []string{"long hair"},
+			character:          []string{"hatsune miku"},
+			generalThreshold:   0.75,
+			characterThreshold: 0.85,
+		},
+		{
+			// the biggest gap of the character scores is 0.2-0.05, its midpoint is below the floor
+			name:               "character mcut floor",
+			data:               []float32{0.7, 0.2, 0.05, 0.01, 0.9, 0.6, 0.4, 0.3, 0.1, 0.2, 0.05, 0.02},
+			opts:               RunOptions{GeneralThreshold: 0.35, CharacterMCut: true},
+			general:            []string{"long hair", "smile", "cat ears"},
+			character:          []string{"hatsune miku"},
+			generalThreshold:   0.35,
+			characterThreshold: characterMCutFloor,
+		},
+		{
+			name:               "relative threshold",
+			opts:               RunOptions{RelativeThreshold: 0.5, CharacterThreshold: 0.85},
+			general:            []string{"long hair", "smile"},
+			character:          []string{"hatsune miku"},
+			generalThreshold:   0.45,
+			characterThreshold: 0.85,
+		},
+		{
+			name:               "max general tags",
+			opts:               RunOptions{GeneralThreshold: 0.05, CharacterThreshold: 0.85, MaxGeneralTags: 2},
+			general:            []string{"long hair", "smile"},
+			character:          []string{"hatsune miku"},
+			generalThreshold:   0.05,
+			characterThreshold: 0.85,
+		},
+		{
+			name:               "epsilon",
+			opts:               RunOptions{GeneralThreshold: 0.6, CharacterThreshold: 0.5, ThresholdEpsilon: 1e-6},
+			general:            []string{"long hair", "smile"},
+			character:          []string{"hatsune miku", "megurine luka"},
+			generalThreshold:   0.6,
+			characterThreshold: 0.5,
+		},
+		{
+			name: "implications",
+			opts: RunOptions{
+				GeneralThreshold:   0.35,
+				CharacterThreshold: 0.85,
+				Implications:       map[string][]string{"cat ears": {"animal ears"}},
+			},
+			general:            []string{"long hair", "smile", "cat ears", "animal ears"},
+			character:          []string{"hatsune miku"},
+			generalThreshold:   0.35,
+			characterThreshold: 0.85,
+		},
+	}
+
+	tags := testTags()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := data
+			if tt.data != nil {
+				input = tt.data
+			}
+			p := tags.buildPredictions(input, tt.opts)
+
+			assertNames(t, "general", p.General, tt.general)
+			assertNames(t, "character", p.Character, tt.character)
+			if len(p.Rating) != 4 {
+				t.Errorf("got %d ratings, want 4", len(p.Rating))
+			}
+			if p.GeneralThresholdUsed != tt.generalThreshold {
+				t.Errorf("general threshold: got %v, want %v", p.GeneralThresholdUsed, tt.generalThreshold)
+			}
+			if p.CharacterThresholdUsed != tt.characterThreshold {
+				t.Errorf("character threshold: got %v, want %v", p.CharacterThresholdUsed, tt.characterThreshold)
+			}
+		})
+	}
+}
+
+// assertNames fails when the keys of scores are not exactly want
+func assertNames(t *testing.T, category string, scores map[string]float32, want []string) {
+	t.Helper()
+
+	if len(scores) != len(want) {
+		t.Errorf("%s: got %v, want %v", category, keys(scores), want)
+		return
+	}
+	for _, name := range want {
+		if _, ok := scores[name]; !ok {
+			t.Errorf("%s: got %v, want %v", category, keys(scores), want)
+			return
+		}
+	}
+}
+
+func keys(scores map[string]float32) []string {
+	var names []string
+	for name := range maps.Keys(scores) {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func TestApplySigmoid(t *testing.T) {
+	// logits of 0 and ±2 map to 0.5, 0.881 and 0.119
+	data := []float32{2, -2, -2, -2, 2, 0, -2, -2, -2, 2, -2, -2}
+
+	p := testTags().buildPredictions(data, RunOptions{GeneralThreshold: 0.4, CharacterThreshold: 0.8, ApplySigmoid: true})
+
+	assertNames(t, "general", p.General, []string{"long hair", "smile"})
+	assertNames(t, "character", p.Character, []string{"hatsune miku"})
+	if got := p.General["smile"]; got != 0.5 {
+		t.Errorf("sigmoid(0): got %v, want 0.5", got)
+	}
+	if got := p.General["long hair"]; got < 0.8807 || got > 0.8808 {
+		t.Errorf("sigmoid(2): got %v, want 0.8808", got)
+	}
+	if got := p.Rating["sensitive"]; got < 0.1192 || got > 0.1193 {
+		t.Errorf("sigmoid(-2): got %v, want 0.1192", got)
+	}
+}
+
+func TestMaxGeneralTags(t *testing.T) {
+	tags := &modelTags{}
+	var data []float32
+	for i := range 50 {
+		tags.names = append(tags.names, fmt.Sprintf("tag %02d", i))
+		tags.generalIndexes = append(tags.generalIndexes, i)
+		data = append(data, 0.5+float32(i)/100)
+	}
+
+	sel := tags.selectTags(data, RunOptions{GeneralThreshold: 0.35, MaxGeneralTags: 30})
+
+	if len(sel.general) != 30 {
+		t.Fatalf("got %d general tags, want 30", len(sel.general))
+	}
+	// the best 30 are the last ones, sorted by descending score
+	for i, index := range sel.general {
+		if want := 49 - i; index != want {
+			t.Fatalf("general tag %d: got index %d, want %d", i, index, want)
+		}
+	}
+}
+
+func TestImplications(t *testing.T) {
+	data := []float32{0.7, 0.2, 0.05, 0.01, 0.1, 0.1, 0.8, 0.1, 0.1, 0.1, 0.1, 0.1}
+
+	tests := []struct {
+		name         string
+		implications map[string][]string
+		want         map[string]float32
+	}{
+		{
+			name:         "chain",
+			implications: map[string][]string{"cat ears": {"animal ears"}, "animal ears": {"ears"}},
+			want:         map[string]float32{"cat ears": 0.8, "animal ears": 0.4, "ears": 0.2},
+		},
+		{
+			name:         "cycle with a predicted tag",
+			implications: map[string][]string{"cat ears": {"animal ears"}, "animal ears": {"cat ears"}},
+			want:         map[string]float32{"cat ears": 0.8, "animal ears": 0.4},
+		},
+		{
+			name:         "cycle of implied tags",
+			implications: map[string][]string{"cat ears": {"a"}, "a": {"b"}, "b": {"a"}},
+			want:         map[string]float32{"cat ears": 0.8, "a": 0.4, "b": 0.2},
+		},
+	}
+
+	tags := testTags()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tags.buildPredictions(data, RunOptions{
+				GeneralThreshold:    0.35,
+				CharacterThreshold:  0.85,
+				Implications:        tt.implications,
+				ImplicationDiscount: 0.5,
+			})
+
+			if !maps.Equal(p.General, tt.want) {
+				t.Errorf("got %v, want %v", p.General, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasses(t *testing.T) {
+	tests := []struct {
+		name                      string
+		score, threshold, epsilon float32
+		want                      bool
+	}{
+		{"at threshold without epsilon", 0.35, 0.35, 0, false},
+		{"above threshold without epsilon", 0.3501, 0.35, 0, true},
+		{"at threshold with epsilon", 0.35, 0.35, 1e-6, true},
+		{"below threshold within epsilon", 0.3499995, 0.35, 1e-6, true},
+		{"below threshold past epsilon", 0.3499, 0.35, 1e-6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := passes(tt.score, tt.threshold, tt.epsilon); got != tt.want {
+				t.Errorf("passes(%v, %v, %v): got %v, want %v", tt.score, tt.threshold, tt.epsilon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatingThreshold(t *testing.T) {
+	data := []float32{0.7, 0.2, 0.05, 0.01, 0.9, 0.6, 0.4, 0.3, 0.1, 0.95, 0.5, 0.2}
+	tags := testTags()
+
+	p := tags.buildPredictions(data, RunOptions{GeneralThreshold: 0.35, CharacterThreshold: 0.85})
+	assertNames(t, "rating", p.Rating, []string{"general", "sensitive", "questionable", "explicit"})
+
+	p = tags.buildPredictions(data, RunOptions{GeneralThreshold: 0.35, CharacterThreshold: 0.85, RatingThreshold: 0.1})
+	assertNames(t, "rating", p.Rating, []string{"general", "sensitive"})
+}
+
+func TestTemperature(t *testing.T) {
+	data := []float32{0, 0.2, 0.5, 0.8, 1, -1e-7, 1 + 1e-6}
+
+	scores := scaleScores(data, RunOptions{Temperature: 2})
+	for i, score := range scores {
+		if math.IsNaN(float64(score)) || score < 0 || score > 1 {
+			t.Errorf("score %d of %v: got %v, want a probability", i, data[i], score)
+		}
+	}
+
+	// a higher temperature pulls the scores towards 0.5
+	if !(scores[1] > 0.2 && scores[1] < 0.5) || scores[2] != 0.5 || !(scores[3] > 0.5 && scores[3] < 0.8) {
+		t.Errorf("got %v, want the scores softened towards 0.5", scores)
+	}
+	if scores[0] != 0 || scores[4] != 1 || scores[5] != 0 || scores[6] != 1 {
+		t.Errorf("got %v, want 0 and 1 kept and the out of range scores clamped", scores)
+	}
+
+	// dividing the logits before the sigmoid gives the same scores
+	logits := []float32{-1.3862944, 0, 1.3862944}
+	withSigmoid := scaleScores(logits, RunOptions{Temperature: 2, ApplySigmoid: true})
+	for i, want := range []float32{scores[1], scores[2], scores[3]} {
+		if math.Abs(float64(withSigmoid[i]-want)) > 1e-6 {
+			t.Errorf("logit %v: got %v, want %v", logits[i], withSigmoid[i], want)
+		}
+	}
+}
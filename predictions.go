@@ -0,0 +1,416 @@
+package gotagger
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// TagScore is a single tag of the predictions
+type TagScore struct {
+	Name     string   `json:"name"`
+	Score    float32  `json:"score"`
+	Category Category `json:"category"`
+}
+
+// RankedTag is a general tag with its position in the predictions, 1 being the most confident
+type RankedTag struct {
+	Name  string  `json:"name"`
+	Rank  int     `json:"rank"`
+	Score float32 `json:"score"`
+}
+
+// RankedNames returns the general tags sorted like Names with their rank, tags with the same score
+// are ranked by name so every rank is unique
+func (p *Predictions) RankedNames() []RankedTag {
+	names := p.Names()
+	ranked := make([]RankedTag, len(names))
+	for i, name := range names {
+		ranked[i] = RankedTag{name, i + 1, p.General[name]}
+	}
+
+	return ranked
+}
+
+// AllSorted returns the tags of every category sorted by descending score
+func (p *Predictions) AllSorted() []TagScore {
+	tags := p.tagScores()
+	slices.SortFunc(tags, func(a, b TagScore) int {
+		return cmp.Or(compareTags(a.Name, a.Score, b.Name, b.Score), cmp.Compare(a.Category, b.Category))
+	})
+
+	return tags
+}
+
+// Flatten returns the tags of every category grouped by category (general, character then rating),
+// each group sorted by descending score.
+//
+// The order is deterministic so it maps cleanly to repeated fields like protobuf ones.
+func (p *Predictions) Flatten() []TagScore {
+	tags := p.tagScores()
+	slices.SortFunc(tags, func(a, b TagScore) int {
+		return cmp.Or(cmp.Compare(a.Category, b.Category), compareTags(a.Name, a.Score, b.Name, b.Score))
+	})
+
+	return tags
+}
+
+func (p *Predictions) tagScores() []TagScore {
+	tags := make([]TagScore, 0, len(p.General)+len(p.Character)+len(p.Rating))
+	for name, score := range p.General {
+		tags = append(tags, TagScore{name, score, CategoryGeneral})
+	}
+	for name, score := range p.Character {
+		tags = append(tags, TagScore{name, score, CategoryCharacter})
+	}
+	for name, score := range p.Rating {
+		tags = append(tags, TagScore{name, score, CategoryRating})
+	}
+
+	return tags
+}
+
+// sortedTagScores returns the tags of scores as TagScores of category, ordered with compareTags
+func sortedTagScores(scores map[string]float32, category Category) []TagScore {
+	tags := make([]TagScore, 0, len(scores))
+	for _, name := range sortedKeys(scores) {
+		tags = append(tags, TagScore{name, scores[name], category})
+	}
+
+	return tags
+}
+
+// compareTags orders tags by descending score, tags with the same score are ordered by name
+func compareTags(aName string, aScore float32, bName string, bScore float32) int {
+	return cmp.Or(cmp.Compare(bScore, aScore), strings.Compare(aName, bName))
+}
+
+// sortedKeys returns the tags of scores ordered with compareTags
+func sortedKeys(scores map[string]float32) []string {
+	keys := slices.Collect(maps.Keys(scores))
+	slices.SortFunc(keys, func(a, b string) int {
+		return compareTags(a, scores[a], b, scores[b])
+	})
+
+	return keys
+}
+
+// Filter returns a copy of the predictions keeping only the general and character tags
+// with a score of at least generalMin and characterMin, ratings are kept as is
+func (p *Predictions) Filter(generalMin, characterMin float32) Predictions {
+	filtered := *p
+	filtered.General = filterScores(p.General, generalMin)
+	filtered.Character = filterScores(p.Character, characterMin)
+	filtered.Rating = maps.Clone(p.Rating)
+	filtered.GeneralThresholdUsed = max(p.GeneralThresholdUsed, generalMin)
+	filtered.CharacterThresholdUsed = max(p.CharacterThresholdUsed, characterMin)
+	filtered.resort()
+
+	return filtered
+}
+
+func filterScores(scores map[string]float32, minScore float32) map[string]float32 {
+	filtered := make(map[string]float32, len(scores))
+	for name, score := range scores {
+		if score >= minScore {
+			filtered[name] = score
+		}
+	}
+
+	return filtered
+}
+
+// InRange returns the general tags with a score between lo and hi inclusive, sorted like Names,
+// useful to find the uncertain tags worth reviewing
+func (p *Predictions) InRange(lo, hi float32) []string {
+	inRange := make(map[string]float32)
+	for name, score := range p.General {
+		if score >= lo && score <= hi {
+			inRange[name] = score
+		}
+	}
+
+	return sortedKeys(inRange)
+}
+
+// WithoutCharacters returns a copy of the predictions without the character tags, p is not modified
+func (p *Predictions) WithoutCharacters() Predictions {
+	split := p.clone()
+	split.Character = map[string]float32{}
+	split.RawNames = p.rawNamesOf(split.General, split.Rating)
+
+	return split
+}
+
+// OnlyCharacters returns a copy of the predictions with only the character tags, p is not modified
+func (p *Predictions) OnlyCharacters() Predictions {
+	split := p.clone()
+	split.General = map[string]float32{}
+	split.Rating = map[string]float32{}
+	split.RawNames = p.rawNamesOf(split.Character)
+	split.SortedGeneral = nil
+
+	return split
+}
+
+// MergeManual returns a copy of the predictions with the manually added general tags at a score of 1,
+// see MergeManualScore
+func (p *Predictions) MergeManual(tags []string) Predictions {
+	return p.MergeManualScore(tags, 1)
+}
+
+// MergeManualScore returns a copy of the predictions with the manually added general tags at score,
+// tags the model scored higher keep their score. p is not modified
+func (p *Predictions) MergeManualScore(tags []string, score float32) Predictions {
+	merged := p.clone()
+	if merged.General == nil {
+		merged.General = make(map[string]float32, len(tags))
+	}
+	for _, name := range tags {
+		merged.General[name] = max(merged.General[name], score)
+	}
+	merged.resort()
+
+	return merged
+}
+
+// clone returns a copy of the predictions that doesn't share its maps
+func (p *Predictions) clone() Predictions {
+	cloned := *p
+	cloned.General = maps.Clone(p.General)
+	cloned.Rating = maps.Clone(p.Rating)
+	cloned.Character = maps.Clone(p.Character)
+	cloned.RawNames = maps.Clone(p.RawNames)
+	cloned.SortedGeneral = slices.Clone(p.SortedGeneral)
+
+	return cloned
+}
+
+// resort updates SortedGeneral after General changed, it stays nil when it wasn't set
+func (p *Predictions) resort() {
+	if p.SortedGeneral != nil {
+		p.SortedGeneral = sortedTagScores(p.General, CategoryGeneral)
+	}
+}
+
+// rawNamesOf returns the RawNames of the tags in groups, nil when p has no RawNames
+func (p *Predictions) rawNamesOf(groups ...map[string]float32) map[string]string {
+	if p.RawNames == nil {
+		return nil
+	}
+
+	rawNames := map[string]string{}
+	for _, group := range groups {
+		for name := range group {
+			if raw, ok := p.RawNames[name]; ok {
+				rawNames[name] = raw
+			}
+		}
+	}
+
+	return rawNames
+}
+
+// OrderedTags returns the character tags followed by the general tags, each sorted by descending score,
+// general tags that are also character tags are only listed once
+func (p *Predictions) OrderedTags() []string {
+	tags := sortedKeys(p.Character)
+	for _, name := range p.Names() {
+		if _, ok := p.Character[name]; !ok {
+			tags = append(tags, name)
+		}
+	}
+
+	return tags
+}
+
+// NormalizedRating returns the ratings scaled so they sum to 1, useful to display them as percentages.
+//
+// The scores are already probabilities so they are divided by their sum (L1 normalization),
+// if every rating is 0 they all get the same share. Rating is not modified.
+func (p *Predictions) NormalizedRating() map[string]float32 {
+	var sum float32
+	for _, score := range p.Rating {
+		sum += score
+	}
+
+	normalized := make(map[string]float32, len(p.Rating))
+	for name, score := range p.Rating {
+		if sum == 0 {
+			normalized[name] = 1 / float32(len(p.Rating))
+			continue
+		}
+		normalized[name] = score / sum
+	}
+
+	return normalized
+}
+
+// Counts returns how many tags of every category are in the predictions
+func (p *Predictions) Counts() (general, character, rating int) {
+	return len(p.General), len(p.Character), len(p.Rating)
+}
+
+// Total returns how many tags are in the predictions across every category
+func (p *Predictions) Total() int {
+	general, character, rating := p.Counts()
+	return general + character + rating
+}
+
+// ConfidenceScore returns the mean score of the k best general tags, or of all of them if there are fewer
+// or k is not positive, as a quality signal of the predictions. It is 0 without general tags.
+func (p *Predictions) ConfidenceScore(k int) float32 {
+	names := p.Names()
+	if k > 0 && k < len(names) {
+		names = names[:k]
+	}
+	if len(names) == 0 {
+		return 0
+	}
+
+	var sum float32
+	for _, name := range names {
+		sum += p.General[name]
+	}
+
+	return sum / float32(len(names))
+}
+
+// defaultBands are the boundaries and names of the bands of Bands without thresholds
+var (
+	defaultBands     = []float32{0.8, 0.5}
+	defaultBandNames = []string{"high", "medium", "low"}
+)
+
+// Bands groups the general tags in confidence bands, each sorted by descending score.
+//
+// thresholds are the lower bounds of the bands, a tag goes in the band of the highest bound it reaches
+// and tags below every bound go in a last band with a bound of 0. Bands are keyed by their bound formatted
+// with %g. Without thresholds the bands are "high" (0.8 and above), "medium" (0.5 and above) and "low".
+// Every band is in the map, even if empty.
+func (p *Predictions) Bands(thresholds ...float32) map[string][]string {
+	bounds := slices.Clone(thresholds)
+	var names []string
+	if len(bounds) == 0 {
+		bounds = defaultBands
+		names = defaultBandNames
+	} else {
+		slices.SortFunc(bounds, func(a, b float32) int { return cmp.Compare(b, a) })
+		for _, bound := range bounds {
+			names = append(names, fmt.Sprintf("%g", bound))
+		}
+		names = append(names, "0")
+	}
+
+	bands := make(map[string][]string, len(names))
+	for _, name := range names {
+		bands[name] = []string{}
+	}
+
+	for _, tag := range p.Names() {
+		band := len(bounds)
+		for i, bound := range bounds {
+			if p.General[tag] >= bound {
+				band = i
+				break
+			}
+		}
+		bands[names[band]] = append(bands[names[band]], tag)
+	}
+
+	return bands
+}
+
+// TypedRating holds the scores of the ratings used by the WD models, absent ratings are 0
+type TypedRating struct {
+	General      float32
+	Sensitive    float32
+	Questionable float32
+	Explicit     float32
+}
+
+// Ratings returns the known ratings as a TypedRating, any other rating label is only in Rating
+func (p *Predictions) Ratings() TypedRating {
+	return TypedRating{
+		General:      p.Rating["general"],
+		Sensitive:    p.Rating["sensitive"],
+		Questionable: p.Rating["questionable"],
+		Explicit:     p.Rating["explicit"],
+	}
+}
+
+// BestCharacter returns the character tag with the highest score, or "" and 0 when there are none.
+// Tags with the same score are ordered by name
+func (p *Predictions) BestCharacter() (string, float32) {
+	return bestTag(p.Character)
+}
+
+// BestRating returns the rating with the highest score, or "" and 0 when there are none.
+// Ratings with the same score are ordered by name
+func (p *Predictions) BestRating() (string, float32) {
+	return bestTag(p.Rating)
+}
+
+// bestTag returns the first tag of scores ordered with compareTags
+func bestTag(scores map[string]float32) (string, float32) {
+	best, bestScore := "", float32(0)
+	for name, score := range scores {
+		if best == "" || compareTags(name, score, best, bestScore) < 0 {
+			best, bestScore = name, score
+		}
+	}
+
+	return best, bestScore
+}
+
+// Diff returns the general tags that are in other but not in p (added) and the ones in p but not in other (removed),
+// both sorted by name
+func (p *Predictions) Diff(other *Predictions) (added, removed []string) {
+	return p.DiffCategory(other, CategoryGeneral)
+}
+
+// NewTagsVsBase returns the general tags that are not in base, like an existing caption, sorted like Names.
+// Tags are compared ignoring case and treating underscores as spaces.
+func (p *Predictions) NewTagsVsBase(base []string) []string {
+	known := make(map[string]struct{}, len(base))
+	for _, name := range base {
+		known[looseTagName(name)] = struct{}{}
+	}
+
+	var tags []string
+	for _, name := range p.Names() {
+		if _, ok := known[looseTagName(name)]; !ok {
+			tags = append(tags, name)
+		}
+	}
+
+	return tags
+}
+
+// looseTagName normalizes a tag name for comparisons ignoring case and underscores
+func looseTagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "_", " "))
+}
+
+// DiffCategory is the same as Diff for the tags of any category
+func (p *Predictions) DiffCategory(other *Predictions, category Category) (added, removed []string) {
+	before := p.scores(category)
+	after := other.scores(category)
+
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			added = append(added, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+
+	return added, removed
+}
@@ -0,0 +1,59 @@
+package gotagger
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// loadRatings replaces the ratings of t with the labels of the ratings dataset at path.
+//
+// The dataset has a name and an index column, the index being the position of the label in the model output.
+// Indexes past the tags dataset extend it, indexes used by another category are rejected.
+func (t *modelTags) loadRatings(path string, format tagsFormat) error {
+	r, closeFile, err := openDataset(path)
+	if err != nil {
+		return err
+	}
+	defer closeFile()
+
+	df := format.readCSV(r)
+	if !slices.Contains(df.Names(), "name") || !slices.Contains(df.Names(), "index") {
+		return fmt.Errorf("ratings dataset %s must have the name and index columns", path)
+	}
+
+	nameCol := df.Col("name").Records()
+	indexCol := df.Col("index").Records()
+
+	ratingIndexes := make([]int, 0, len(nameCol))
+	for i, record := range indexCol {
+		index, err := strconv.Atoi(record)
+		if err != nil || index < 0 {
+			return fmt.Errorf("invalid index %q of rating %s in %s", record, nameCol[i], path)
+		}
+
+		for _, c := range []Category{CategoryGeneral, CategoryCharacter, CategoryArtist, CategoryCopyright, CategoryMeta} {
+			if slices.Contains(t.indexes(c), index) {
+				return fmt.Errorf("rating %s uses index %d which is a %s tag", nameCol[i], index, c)
+			}
+		}
+		if slices.Contains(ratingIndexes, index) {
+			return fmt.Errorf("rating %s uses index %d which is already a rating", nameCol[i], index)
+		}
+
+		for len(t.names) <= index {
+			t.names = append(t.names, "")
+			t.rawNames = append(t.rawNames, "")
+			if t.counts != nil {
+				t.counts = append(t.counts, 0)
+			}
+		}
+		t.names[index] = nameCol[i]
+		t.rawNames[index] = nameCol[i]
+		ratingIndexes = append(ratingIndexes, index)
+	}
+
+	t.ratingIndexes = ratingIndexes
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry of RunWithRetry, it doubles on every retry
+const retryBackoff = 100 * time.Millisecond
+
+// RunWithRetry is RunWithOptions retrying the batches that fail inside ORT, like an out of memory error
+// on a busy GPU, up to retries times each with an exponential backoff. For dynamic batch models every
+// retry also halves the batch. Errors of the images themselves are not retried.
+//
+// On failure the predictions of the images before the failed batch are returned along with the error.
+func (s *TaggerSession) RunWithRetry(images []image.Image, opts RunOptions, retries int) ([]Predictions, error) {
+	batch, err := s.chunkSize(len(images), opts.MaxBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	predictions := make([]Predictions, 0, len(images))
+	for start := 0; start < len(images); {
+		size := batch
+		for attempt := 0; ; attempt++ {
+			end := min(start+size, len(images))
+
+			chunkOpts := opts
+			chunkOpts.MaxBatch = size
+			out, err := s.RunWithOptions(images[start:end], chunkOpts)
+			if err == nil {
+				predictions = append(predictions, out...)
+				start = end
+				break
+			}
+
+			var runErr *RunError
+			if !errors.As(err, &runErr) || attempt >= retries {
+				return predictions, fmt.Errorf("images %d to %d: %w", start, end-1, err)
+			}
+
+			s.logger.Warn("retrying failed batch", "start", start, "end", end, "attempt", attempt+1, "error", err)
+			time.Sleep(retryBackoff << attempt)
+			if s.batchSize == -1 && size > 1 {
+				size /= 2
+			}
+		}
+	}
+
+	return predictions, nil
+}
@@ -0,0 +1,101 @@
+package gotagger
+
+import (
+	"fmt"
+	"image"
+)
+
+// RunRGB tags raw frames, each one width x height pixels of 3 bytes in the given order (ChannelOrderRGB or
+// ChannelOrderBGR), row by row without padding, like the output of a frame grabber. They are padded and resized
+// like images given to RunWithOptions, skipping the decoding and the color model conversion.
+// Frames already at the model size are written straight into the input. RunOptions.Cache is not used.
+func (s *TaggerSession) RunRGB(
+	pixels [][]byte,
+	width, height int,
+	order ChannelOrder,
+	opts RunOptions,
+) ([]Predictions, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("%w: size is %dx%d", ErrEmptyImage, width, height)
+	}
+	if order != ChannelOrderRGB && order != ChannelOrderBGR {
+		return nil, fmt.Errorf("unsupported channel order %s of the frames, expected RGB or BGR", order)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	direct := s.isModelSize(width, height, opts)
+	raw, err := s.runBatches(len(pixels), opts, func(i int) ([]float32, error) {
+		if err := checkFrameSize(pixels[i], width, height); err != nil {
+			return nil, err
+		}
+
+		if direct {
+			return frameToInput(pixels[i], order, s.channelOrder), nil
+		}
+
+		return s.prepareImage(frameToNRGBA(pixels[i], width, height, order), opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.predictionsFromRaw(raw, opts)
+}
+
+// isModelSize returns whether preprocessing a width x height frame with opts leaves it as is,
+// so its pixels can be copied into the input without going through an image
+func (s *TaggerSession) isModelSize(width, height int, opts RunOptions) bool {
+	if width != s.targetSize || height != s.targetSize || opts.PreprocessCache != nil {
+		return false
+	}
+
+	return width >= opts.MinResolution && (opts.MaxInputDimension <= 0 || opts.MaxInputDimension >= width)
+}
+
+// checkFrameSize returns an error when pixels is not a width x height frame of 3 bytes per pixel
+func checkFrameSize(pixels []byte, width, height int) error {
+	if len(pixels) != 3*width*height {
+		return fmt.Errorf(
+			"frame has %d bytes, expected %d for %dx%d pixels",
+			len(pixels),
+			3*width*height,
+			width,
+			height,
+		)
+	}
+
+	return nil
+}
+
+// frameToInput converts the packed bytes of a frame in order to the input of a model expecting modelOrder
+func frameToInput(pixels []byte, order, modelOrder ChannelOrder) []float32 {
+	data := make([]float32, len(pixels))
+	swap := order != modelOrder
+	for i := 0; i < len(pixels); i += 3 {
+		data[i+1] = float32(pixels[i+1])
+		if swap {
+			data[i], data[i+2] = float32(pixels[i+2]), float32(pixels[i])
+		} else {
+			data[i], data[i+2] = float32(pixels[i]), float32(pixels[i+2])
+		}
+	}
+
+	return data
+}
+
+// frameToNRGBA copies the packed bytes of a width x height frame in order into an opaque NRGBA image
+func frameToNRGBA(pixels []byte, width, height int, order ChannelOrder) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		if order == ChannelOrderBGR {
+			img.Pix[4*i], img.Pix[4*i+1], img.Pix[4*i+2] = pixels[3*i+2], pixels[3*i+1], pixels[3*i]
+		} else {
+			copy(img.Pix[4*i:4*i+3], pixels[3*i:3*i+3])
+		}
+		img.Pix[4*i+3] = 0xff
+	}
+
+	return img
+}
@@ -0,0 +1,44 @@
+package gotagger
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFrameToInput(t *testing.T) {
+	const size = 8
+	rgb := make([]byte, 3*size*size)
+	bgr := make([]byte, len(rgb))
+	for i := 0; i < len(rgb); i += 3 {
+		rgb[i], rgb[i+1], rgb[i+2] = byte(i), byte(i*3), byte(i*7)
+		bgr[i], bgr[i+1], bgr[i+2] = rgb[i+2], rgb[i+1], rgb[i]
+	}
+
+	for _, modelOrder := range []ChannelOrder{ChannelOrderBGR, ChannelOrderRGB} {
+		// the direct copy matches preprocessing the frame as an image
+		want := prepareInput(frameToNRGBA(rgb, size, size, ChannelOrderRGB), size, modelOrder, RunOptions{})
+
+		for _, frame := range []struct {
+			order  ChannelOrder
+			pixels []byte
+		}{{ChannelOrderRGB, rgb}, {ChannelOrderBGR, bgr}} {
+			if got := frameToInput(frame.pixels, frame.order, modelOrder); !slices.Equal(got, want) {
+				t.Errorf("%s frame for a %s model: input differs from the preprocessed image", frame.order, modelOrder)
+			}
+
+			img := frameToNRGBA(frame.pixels, size, size, frame.order)
+			if got := prepareInput(img, size, modelOrder, RunOptions{}); !slices.Equal(got, want) {
+				t.Errorf("%s frame for a %s model: image differs from the RGB one", frame.order, modelOrder)
+			}
+		}
+	}
+}
+
+func TestCheckFrameSize(t *testing.T) {
+	if err := checkFrameSize(make([]byte, 3*4*2), 4, 2); err != nil {
+		t.Errorf("4x2 frame: got %v, want nil", err)
+	}
+	if err := checkFrameSize(make([]byte, 4*4*2), 4, 2); err == nil {
+		t.Error("4x2 frame of 4 bytes per pixel: expected an error")
+	}
+}
@@ -0,0 +1,94 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+// assertImageError fails when err is not an ImageError of image index matching target
+func assertImageError(t *testing.T, err error, index int, target error) {
+	t.Helper()
+
+	var imageErr *ImageError
+	if !errors.As(err, &imageErr) || imageErr.Index != index || !errors.Is(err, target) {
+		t.Errorf("image %d: got %v, want an ImageError matching %v", index, err, target)
+	}
+}
+
+func TestRunEachMinResolution(t *testing.T) {
+	s := destroyedSession()
+	images := []image.Image{
+		image.NewNRGBA(image.Rect(0, 0, 64, 64)),
+		image.NewNRGBA(image.Rect(0, 0, 32, 32)),
+		image.NewNRGBA(image.Rect(0, 0, 96, 64)),
+	}
+
+	// the small image is rejected on its own, the others reach the session
+	predictions, errs := s.RunEach(images, RunOptions{MinResolution: 64})
+
+	if len(predictions) != 3 || len(errs) != 3 {
+		t.Fatalf("got %d predictions and %d errors, want 3", len(predictions), len(errs))
+	}
+	assertImageError(t, errs[1], 1, ErrLowResolution)
+	for _, i := range []int{0, 2} {
+		if !errors.Is(errs[i], ErrSessionDestroyed) {
+			t.Errorf("image %d: got %v, want the run error", i, errs[i])
+		}
+	}
+}
+
+func TestRunEachTagsValidImages(t *testing.T) {
+	model, tags := testModel(t)
+
+	s, err := New(model, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Destroy()
+
+	images := []image.Image{
+		image.NewNRGBA(image.Rect(0, 0, 32, 32)),
+		image.NewNRGBA(image.Rect(0, 0, 128, 128)),
+	}
+	predictions, errs := s.RunEach(images, RunOptions{MinResolution: 64})
+
+	assertImageError(t, errs[0], 0, ErrLowResolution)
+	if errs[1] != nil || predictions[1].Rating == nil {
+		t.Errorf("image 1: got %v, want it tagged", errs[1])
+	}
+}
+
+func TestRunEachEmptyImage(t *testing.T) {
+	s := destroyedSession()
+	images := []image.Image{
+		image.NewNRGBA(image.Rect(0, 0, 0, 0)),
+		image.NewNRGBA(image.Rect(0, 0, 16, 16)),
+		nil,
+	}
+
+	_, errs := s.RunEach(images, RunOptions{})
+
+	assertImageError(t, errs[0], 0, ErrEmptyImage)
+	assertImageError(t, errs[2], 2, ErrEmptyImage)
+	if !errors.Is(errs[1], ErrSessionDestroyed) {
+		t.Errorf("image 1: got %v, want the run error", errs[1])
+	}
+}
+
+func TestRunEachGrayscale(t *testing.T) {
+	s := destroyedSession()
+	images := []image.Image{
+		image.NewNRGBA(image.Rect(0, 0, 16, 16)),
+		image.NewGray(image.Rect(0, 0, 16, 16)),
+		image.NewGray16(image.Rect(0, 0, 16, 16)),
+	}
+
+	_, errs := s.RunEach(images, RunOptions{RejectGrayscale: true})
+
+	assertImageError(t, errs[1], 1, ErrGrayscale)
+	assertImageError(t, errs[2], 2, ErrGrayscale)
+	if !errors.Is(errs[0], ErrSessionDestroyed) {
+		t.Errorf("image 0: got %v, want the run error", errs[0])
+	}
+}
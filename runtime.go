@@ -0,0 +1,65 @@
+package gotagger
+
+import (
+	"fmt"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// LogLevel is the minimum severity of the messages ORT writes to stderr
+type LogLevel int
+
+const (
+	// LogLevelError only logs errors, it is the ORT default
+	LogLevelError LogLevel = iota
+	// LogLevelVerbose logs everything, including the provider and graph optimization details
+	LogLevelVerbose
+	// LogLevelInfo logs informational messages, like the provider assigned to every node
+	LogLevelInfo
+	// LogLevelWarning logs warnings, like a provider falling back to CPU
+	LogLevelWarning
+	// LogLevelFatal only logs fatal errors
+	LogLevelFatal
+)
+
+func (l LogLevel) option() ort.EnvironmentOption {
+	switch l {
+	case LogLevelVerbose:
+		return ort.WithLogLevelVerbose()
+	case LogLevelInfo:
+		return ort.WithLogLevelInfo()
+	case LogLevelWarning:
+		return ort.WithLogLevelWarning()
+	case LogLevelFatal:
+		return ort.WithLogLevelFatal()
+	}
+	return ort.WithLogLevelError()
+}
+
+// InitRuntime sets the ORT shared library path and initializes the ORT environment,
+// it must be called once before creating any session.
+//
+// An empty libPath keeps the default library path of onnxruntime_go.
+func InitRuntime(libPath string) error {
+	return InitRuntimeWithLogLevel(libPath, LogLevelError)
+}
+
+// InitRuntimeWithLogLevel is the same as InitRuntime but sets the ORT logging level.
+//
+// ORT writes its logs to stderr, onnxruntime_go has no log callback so they can't go through Options.Logger.
+func InitRuntimeWithLogLevel(libPath string, level LogLevel) error {
+	if libPath != "" {
+		ort.SetSharedLibraryPath(libPath)
+	}
+
+	if err := ort.InitializeEnvironment(level.option()); err != nil {
+		return fmt.Errorf("error while initializing ORT environment: %w", err)
+	}
+
+	return nil
+}
+
+// ShutdownRuntime destroys the ORT environment, every session must be destroyed before calling it
+func ShutdownRuntime() error {
+	return ort.DestroyEnvironment()
+}
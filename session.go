@@ -0,0 +1,245 @@
+package gotagger
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// Provider is the ORT execution provider used by the session
+type Provider int
+
+const (
+	// ProviderCPU runs the model on the CPU, it is the default
+	ProviderCPU Provider = iota
+	// ProviderCUDA runs the model on a NVIDIA GPU, it needs a CUDA enabled ORT build
+	ProviderCUDA
+	// ProviderDirectML runs the model on a DirectX 12 GPU, it needs a DirectML enabled ORT build
+	ProviderDirectML
+)
+
+func (p Provider) String() string {
+	switch p {
+	case ProviderCPU:
+		return "CPU"
+	case ProviderCUDA:
+		return "CUDA"
+	case ProviderDirectML:
+		return "DirectML"
+	}
+	return fmt.Sprintf("Provider(%d)", int(p))
+}
+
+// sessionOptions builds the ORT session options for opts, it returns nil when the defaults are enough
+func sessionOptions(opts Options) (*ort.SessionOptions, error) {
+	if opts.Provider != ProviderCUDA && (opts.GPUMemLimit != 0 || opts.ArenaExtendStrategy != "") {
+		return nil, fmt.Errorf(
+			"GPUMemLimit and ArenaExtendStrategy need the CUDA provider, the session uses %s",
+			opts.Provider,
+		)
+	}
+
+	if opts.Provider == ProviderCPU {
+		return nil, nil
+	}
+
+	sessionOpts, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("error while creating session options: %w", err)
+	}
+
+	switch opts.Provider {
+	case ProviderCUDA:
+		err = appendCUDA(sessionOpts, opts)
+	case ProviderDirectML:
+		err = sessionOpts.AppendExecutionProviderDirectML(opts.DeviceID)
+	default:
+		err = fmt.Errorf("unknown provider %s", opts.Provider)
+	}
+	if err != nil {
+		sessionOpts.Destroy()
+		return nil, fmt.Errorf("error while setting the %s provider: %w", opts.Provider, err)
+	}
+
+	return sessionOpts, nil
+}
+
+func appendCUDA(sessionOpts *ort.SessionOptions, opts Options) error {
+	cudaOpts, err := ort.NewCUDAProviderOptions()
+	if err != nil {
+		return err
+	}
+	defer cudaOpts.Destroy()
+
+	settings := map[string]string{
+		"device_id": strconv.Itoa(opts.DeviceID),
+	}
+	if opts.GPUMemLimit != 0 {
+		settings["gpu_mem_limit"] = strconv.FormatUint(opts.GPUMemLimit, 10)
+	}
+	if opts.ArenaExtendStrategy != "" {
+		settings["arena_extend_strategy"] = opts.ArenaExtendStrategy
+	}
+
+	if err := cudaOpts.Update(settings); err != nil {
+		return err
+	}
+
+	return sessionOpts.AppendExecutionProviderCUDA(cudaOpts)
+}
+
+// newSession creates the ORT session of the model.
+//
+// The plain float32 session is used when possible so TaggerSession.Session keeps working,
+// float16 models and custom session options need the advanced session.
+func newSession(
+	modelPath string,
+	inputNames []string,
+	outputNames []string,
+	opts Options,
+) (*ort.DynamicSession[float32, float32], *ort.DynamicAdvancedSession, error) {
+	sessionOpts, err := sessionOptions(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sessionOpts == nil && !opts.Float16 && !opts.Float16Output {
+		session, err := ort.NewDynamicSession[float32, float32](modelPath, inputNames, outputNames)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error while starting new dynamic session: %w", err)
+		}
+		return session, nil, nil
+	}
+
+	if sessionOpts != nil {
+		defer sessionOpts.Destroy()
+	}
+
+	advanced, err := ort.NewDynamicAdvancedSession(modelPath, inputNames, outputNames, sessionOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while starting new dynamic session: %w", err)
+	}
+
+	return nil, advanced, nil
+}
+
+// checkDataTypes returns an error when the input or an output of the model is not of the float type of the session,
+// like the int8/uint8 outputs of fully quantized models which are not dequantized
+func checkDataTypes(input ort.InputOutputInfo, outputs []ort.InputOutputInfo, float16Input, float16Output bool) error {
+	for _, info := range append([]ort.InputOutputInfo{input}, outputs...) {
+		var expected ort.TensorElementDataType = ort.TensorElementDataTypeFloat
+		if (info.Name == input.Name && float16Input) || (info.Name != input.Name && float16Output) {
+			expected = ort.TensorElementDataTypeFloat16
+		}
+		if info.DataType == expected {
+			continue
+		}
+
+		switch info.DataType {
+		case ort.TensorElementDataTypeFloat, ort.TensorElementDataTypeFloat16:
+			return fmt.Errorf(
+				"%s has type %s, set Options.Float16 or Options.Float16Output accordingly",
+				info.Name,
+				info.DataType,
+			)
+		}
+		return fmt.Errorf(
+			"unsupported type %s of %s, only float32 and float16 models are supported, "+
+				"quantized models must dequantize their input and output in the graph",
+			info.DataType,
+			info.Name,
+		)
+	}
+
+	return nil
+}
+
+// run runs whichever ORT session was created
+func (s *TaggerSession) run(inputs []ort.Value, outputs []ort.Value) error {
+	if s.advanced != nil {
+		return s.advanced.Run(inputs, outputs)
+	}
+
+	in := make([]*ort.Tensor[float32], len(inputs))
+	for i, v := range inputs {
+		in[i] = v.(*ort.Tensor[float32])
+	}
+	out := make([]*ort.Tensor[float32], len(outputs))
+	for i, v := range outputs {
+		out[i] = v.(*ort.Tensor[float32])
+	}
+
+	return s.Session.Run(in, out)
+}
+
+// newTensor creates a tensor of the type expected by the model, data is float32 and converted if needed
+func (s *TaggerSession) newTensor(shape ort.Shape, data []float32) (ort.Value, error) {
+	if s.float16Input {
+		return ort.NewCustomDataTensor(
+			shape,
+			encodeFloat16(data[:shape.FlattenedSize()]),
+			ort.TensorElementDataTypeFloat16,
+		)
+	}
+
+	return ort.NewTensor(shape, data)
+}
+
+// newEmptyTensor creates an output tensor of the type produced by the model
+func (s *TaggerSession) newEmptyTensor(shape ort.Shape) (ort.Value, error) {
+	if s.float16Output {
+		return ort.NewCustomDataTensor(
+			shape,
+			make([]byte, 2*shape.FlattenedSize()),
+			ort.TensorElementDataTypeFloat16,
+		)
+	}
+
+	return ort.NewEmptyTensor[float32](shape)
+}
+
+// setTensorData overwrites the data of a tensor created by newTensor, converting it if needed
+func setTensorData(v ort.Value, data []float32) {
+	switch t := v.(type) {
+	case *ort.Tensor[float32]:
+		copy(t.GetData(), data)
+	case *ort.CustomDataTensor:
+		copy(t.GetData(), encodeFloat16(data))
+	}
+}
+
+// tensorData returns a float32 copy of the data of a tensor created by newTensor or newEmptyTensor
+func tensorData(v ort.Value) []float32 {
+	switch t := v.(type) {
+	case *ort.Tensor[float32]:
+		return slices.Clone(t.GetData())
+	case *ort.CustomDataTensor:
+		return decodeFloat16(t.GetData())
+	}
+	return nil
+}
+
+// AvailableProviders returns the providers supported by the loaded ORT shared library, CPU is always available.
+//
+// The ORT environment must be initialized first, each provider is probed by attaching it to throwaway
+// session options so a provider can still fail later if the device itself is missing.
+func AvailableProviders() ([]Provider, error) {
+	if !ort.IsInitialized() {
+		return nil, ort.NotInitializedError
+	}
+
+	providers := []Provider{ProviderCPU}
+	for _, provider := range []Provider{ProviderCUDA, ProviderDirectML} {
+		sessionOpts, err := sessionOptions(Options{Provider: provider})
+		if err != nil {
+			continue
+		}
+
+		sessionOpts.Destroy()
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,136 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestDestroyZeroSession(t *testing.T) {
+	var s TaggerSession
+	if err := s.Destroy(); err != nil {
+		t.Fatalf("Destroy on the zero session: %v", err)
+	}
+}
+
+func TestDestroyTwice(t *testing.T) {
+	s := destroyedSession()
+	c := s
+
+	for i := 0; i < 2; i++ {
+		if err := s.Destroy(); err != nil {
+			t.Fatalf("Destroy %d: %v", i+1, err)
+		}
+		if err := c.Destroy(); err != nil {
+			t.Fatalf("Destroy %d on a copy: %v", i+1, err)
+		}
+	}
+}
+
+func TestDestroyCopies(t *testing.T) {
+	model, tags := testModel(t)
+
+	s, err := New(model, tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s
+
+	if err := s.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Destroy(); err != nil {
+		t.Fatalf("Destroy on a copy: %v", err)
+	}
+	if err := s.Destroy(); err != nil {
+		t.Fatalf("second Destroy: %v", err)
+	}
+
+	if _, err := c.RunWithOptions([]image.Image{image.NewNRGBA(image.Rect(0, 0, 64, 64))}, RunOptions{}); !errors.Is(err, ErrSessionDestroyed) {
+		t.Fatalf("Run on a destroyed copy: got %v, want ErrSessionDestroyed", err)
+	}
+}
+
+func TestNewBadTagsCleanup(t *testing.T) {
+	model, _ := testModel(t)
+
+	s, err := New(model, filepath.Join(t.TempDir(), "missing.csv"))
+	if err == nil {
+		s.Destroy()
+		t.Fatal("expected an error for a missing tags file")
+	}
+	if err := s.Destroy(); err != nil {
+		t.Fatalf("Destroy on the session returned with the error: %v", err)
+	}
+}
+
+func TestLoadSessionTagsCleanup(t *testing.T) {
+	dir := t.TempDir()
+	full := filepath.Join(dir, "full.csv")
+	generalOnly := filepath.Join(dir, "general.csv")
+	os.WriteFile(full, []byte("tag_id,name,category,count\n1,general,9,10\n2,long_hair,0,10\n3,hatsune_miku,4,10\n"), 0o644)
+	os.WriteFile(generalOnly, []byte("tag_id,name,category,count\n1,long_hair,0,10\n2,smile,0,10\n3,hat,0,10\n"), 0o644)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name    string
+		path    string
+		output  ort.Shape
+		strict  bool
+		wantErr bool
+	}{
+		{"valid", full, ort.NewShape(1, 3), true, false},
+		{"missing file", filepath.Join(dir, "missing.csv"), ort.NewShape(1, 3), false, true},
+		{"tag count", full, ort.NewShape(1, 4), false, true},
+		{"strict categories", generalOnly, ort.NewShape(1, 3), true, true},
+		{"lax categories", generalOnly, ort.NewShape(1, 3), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destroyed := 0
+			load := func() (modelTags, error) {
+				tags, err := loadTags(tt.path, tagsFormat{})
+				tags.tagsPath = tt.path
+				return tags, err
+			}
+
+			_, err := loadSessionTags(load, tt.output, tt.strict, logger, func() error {
+				destroyed++
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v", err, tt.wantErr)
+			}
+			want := 0
+			if tt.wantErr {
+				want = 1
+			}
+			if destroyed != want {
+				t.Errorf("session destroyed %d times, want %d", destroyed, want)
+			}
+		})
+	}
+}
+
+func TestSessionOptionsCUDASettings(t *testing.T) {
+	for _, opts := range []Options{
+		{GPUMemLimit: 1 << 30},
+		{ArenaExtendStrategy: "kSameAsRequested"},
+		{Provider: ProviderDirectML, GPUMemLimit: 1 << 30},
+	} {
+		if _, err := sessionOptions(opts); err == nil {
+			t.Errorf("%+v: expected an error without the CUDA provider", opts)
+		}
+	}
+
+	sessionOpts, err := sessionOptions(Options{})
+	if err != nil || sessionOpts != nil {
+		t.Errorf("CPU defaults: got %v, %v, want nil options", sessionOpts, err)
+	}
+}
@@ -0,0 +1,68 @@
+package gotagger
+
+import "math"
+
+// CosineSimilarity compares two raw outputs returned by RunRaw by the cosine of their general tags vectors,
+// 1 means the same tags with proportional scores and 0 means no tags in common
+func (s *TaggerSession) CosineSimilarity(a, b []float32) float32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dot, normA, normB float64
+	for _, index := range s.generalIndexes {
+		if index >= len(a) || index >= len(b) {
+			continue
+		}
+
+		dot += float64(a[index]) * float64(b[index])
+		normA += float64(a[index]) * float64(a[index])
+		normB += float64(b[index]) * float64(b[index])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// TagSetSimilarity compares the general tags of two predictions by their Jaccard index, the amount of tags
+// in both divided by the amount of tags in any of them. Scores are ignored, see WeightedTagSetSimilarity.
+//
+// 1 means the same tags and 0 means no tags in common, two predictions without tags are the same.
+func TagSetSimilarity(a, b *Predictions) float32 {
+	if len(a.General) == 0 && len(b.General) == 0 {
+		return 1
+	}
+
+	common := 0
+	for name := range a.General {
+		if _, ok := b.General[name]; ok {
+			common++
+		}
+	}
+
+	return float32(common) / float32(len(a.General)+len(b.General)-common)
+}
+
+// WeightedTagSetSimilarity is TagSetSimilarity weighted by confidence: the sum of the lowest score of every tag
+// divided by the sum of its highest score, a tag missing from one of the predictions scoring 0 there
+func WeightedTagSetSimilarity(a, b *Predictions) float32 {
+	var minSum, maxSum float64
+	for name, scoreA := range a.General {
+		scoreB := b.General[name]
+		minSum += float64(min(scoreA, scoreB))
+		maxSum += float64(max(scoreA, scoreB))
+	}
+	for name, scoreB := range b.General {
+		if _, ok := a.General[name]; !ok {
+			maxSum += float64(scoreB)
+		}
+	}
+
+	if maxSum == 0 {
+		return 1
+	}
+
+	return float32(minSum / maxSum)
+}
@@ -0,0 +1,110 @@
+package gotagger
+
+import "math"
+
+// StatsBuckets is the amount of buckets in a Histogram, each covering 0.1 of probability
+const StatsBuckets = 10
+
+// Histogram counts how many predictions fall in each 0.1 probability bucket,
+// bucket i covers [i/10, (i+1)/10) and the last one also includes 1
+type Histogram [StatsBuckets]int
+
+func (h *Histogram) add(pred float32) {
+	bucket := int(pred * StatsBuckets)
+	h[min(max(bucket, 0), StatsBuckets-1)]++
+}
+
+// Stats are the probability histograms of every category over a set of raw outputs
+type Stats struct {
+	// Images is the amount of outputs the stats were computed from
+	Images    int
+	General   Histogram
+	Character Histogram
+	Rating    Histogram
+}
+
+// Stats computes the histogram of every category over raw outputs returned by RunRaw
+func (s *TaggerSession) Stats(raw [][]float32) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := Stats{Images: len(raw)}
+	for _, data := range raw {
+		for _, index := range s.generalIndexes {
+			if index < len(data) {
+				stats.General.add(data[index])
+			}
+		}
+		for _, index := range s.characterIndexes {
+			if index < len(data) {
+				stats.Character.add(data[index])
+			}
+		}
+		for _, index := range s.ratingIndexes {
+			if index < len(data) {
+				stats.Rating.add(data[index])
+			}
+		}
+	}
+
+	return stats
+}
+
+// ThresholdSummary is the spread of a threshold over a batch of predictions
+type ThresholdSummary struct {
+	Min    float32
+	Max    float32
+	Mean   float32
+	StdDev float32
+}
+
+// ThresholdReport summarizes the thresholds applied to a batch of predictions, with mcut a wide spread
+// points to images with unusual tag densities worth checking
+type ThresholdReport struct {
+	// Images is the amount of predictions the report was computed from
+	Images    int
+	General   ThresholdSummary
+	Character ThresholdSummary
+}
+
+// NewThresholdReport summarizes the GeneralThresholdUsed and CharacterThresholdUsed of predictions
+func NewThresholdReport(predictions []Predictions) ThresholdReport {
+	general := make([]float32, len(predictions))
+	character := make([]float32, len(predictions))
+	for i, p := range predictions {
+		general[i] = p.GeneralThresholdUsed
+		character[i] = p.CharacterThresholdUsed
+	}
+
+	return ThresholdReport{
+		Images:    len(predictions),
+		General:   summarize(general),
+		Character: summarize(character),
+	}
+}
+
+// summarize returns the spread of values, all zero when there are none
+func summarize(values []float32) ThresholdSummary {
+	if len(values) == 0 {
+		return ThresholdSummary{}
+	}
+
+	summary := ThresholdSummary{Min: values[0], Max: values[0]}
+	var sum float64
+	for _, v := range values {
+		summary.Min = min(summary.Min, v)
+		summary.Max = max(summary.Max, v)
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+
+	summary.Mean = float32(mean)
+	summary.StdDev = float32(math.Sqrt(variance / float64(len(values))))
+
+	return summary
+}
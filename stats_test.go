@@ -0,0 +1,53 @@
+package gotagger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewThresholdReport(t *testing.T) {
+	tags := testTags()
+	opts := RunOptions{GeneralMCut: true, CharacterThreshold: 0.85}
+
+	// the biggest gaps of the general scores put the mcut thresholds at 0.8, 0.5 and 0.2
+	var predictions []Predictions
+	for _, general := range [][]float32{
+		{0.9, 0.7, 0.65, 0.6, 0.55},
+		{0.9, 0.85, 0.8, 0.2, 0.15},
+		{0.3, 0.1, 0.08, 0.05, 0.02},
+	} {
+		data := append([]float32{0.7, 0.2, 0.05, 0.01}, general...)
+		data = append(data, 0.95, 0.5, 0.2)
+		predictions = append(predictions, tags.buildPredictions(data, opts))
+	}
+
+	report := NewThresholdReport(predictions)
+
+	if report.Images != 3 {
+		t.Errorf("images: got %d, want 3", report.Images)
+	}
+	assertSummary(t, "general", report.General, ThresholdSummary{Min: 0.2, Max: 0.8, Mean: 0.5, StdDev: 0.2449})
+	assertSummary(t, "character", report.Character, ThresholdSummary{Min: 0.85, Max: 0.85, Mean: 0.85})
+
+	if empty := NewThresholdReport(nil); empty != (ThresholdReport{}) {
+		t.Errorf("empty batch: got %+v, want the zero report", empty)
+	}
+}
+
+func assertSummary(t *testing.T, category string, got, want ThresholdSummary) {
+	t.Helper()
+
+	for _, v := range []struct {
+		name      string
+		got, want float32
+	}{
+		{"min", got.Min, want.Min},
+		{"max", got.Max, want.Max},
+		{"mean", got.Mean, want.Mean},
+		{"stddev", got.StdDev, want.StdDev},
+	} {
+		if math.Abs(float64(v.got-v.want)) > 1e-4 {
+			t.Errorf("%s %s: got %v, want %v", category, v.name, v.got, v.want)
+		}
+	}
+}
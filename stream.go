@@ -0,0 +1,102 @@
+package gotagger
+
+import (
+	"context"
+	"image"
+	"time"
+)
+
+// streamBatchSize is the batch size of RunStream for dynamic batch models without MaxBatch
+const streamBatchSize = 32
+
+// StreamResult is a single image result of RunStream
+type StreamResult struct {
+	// Index is the position of the image in the input channel
+	Index       int
+	Predictions Predictions
+	Err         error
+}
+
+// RunStream tags the images received from images in batches, sending a result per image in input order.
+//
+// A batch is run when it is full, when images is closed or, if RunOptions.FlushInterval is set,
+// when that much time passed since its first image arrived. The returned channel is closed once images
+// is closed and every result was sent, or when ctx is canceled.
+func (s *TaggerSession) RunStream(ctx context.Context, images <-chan image.Image, opts RunOptions) <-chan StreamResult {
+	results := make(chan StreamResult)
+
+	go func() {
+		defer close(results)
+
+		batch, err := s.chunkSize(streamBatchSize, opts.MaxBatch)
+		if err != nil {
+			select {
+			case results <- StreamResult{Index: 0, Err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		var (
+			pending []image.Image
+			next    int
+			timer   *time.Timer
+			timerC  <-chan time.Time
+		)
+
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timerC = nil, nil
+			}
+			if len(pending) == 0 {
+				return true
+			}
+
+			predictions, err := s.RunWithOptions(pending, opts)
+			for i := range pending {
+				r := StreamResult{Index: next + i, Err: err}
+				if err == nil {
+					r.Predictions = predictions[i]
+				}
+
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return false
+				}
+			}
+
+			next += len(pending)
+			pending = pending[:0]
+			return true
+		}
+
+		for {
+			select {
+			case img, ok := <-images:
+				if !ok {
+					flush()
+					return
+				}
+
+				pending = append(pending, img)
+				if len(pending) == 1 && opts.FlushInterval > 0 {
+					timer = time.NewTimer(opts.FlushInterval)
+					timerC = timer.C
+				}
+				if len(pending) >= batch && !flush() {
+					return
+				}
+			case <-timerC:
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results
+}
@@ -0,0 +1,21 @@
+package gotagger
+
+import "image"
+
+// Tagger is the tagging surface of TaggerSession.
+//
+// Depend on it instead of *TaggerSession in your code so tests can inject a fake without a model or ORT.
+type Tagger interface {
+	Run(
+		images []image.Image,
+		generalThreshold float32,
+		characterThreshold float32,
+		generalMCutEnabled bool,
+		characterMCutEnabled bool,
+	) ([]Predictions, error)
+	RunWithOptions(images []image.Image, opts RunOptions) ([]Predictions, error)
+	RunOne(img image.Image, opts RunOptions) (Predictions, error)
+	Destroy() error
+}
+
+var _ Tagger = (*TaggerSession)(nil)
@@ -0,0 +1,60 @@
+package gotagger
+
+import "slices"
+
+// minSimilarTagLength is the minimum length in runes of both tags merged by RunOptions.MergeSimilarTags,
+// shorter tags differ by a letter or two while meaning different things (like "hat" and "cat")
+const minSimilarTagLength = 5
+
+// mergeSimilar drops the general tags within maxDistance edits of a tag with a higher score,
+// keeping the order of general
+func (t *modelTags) mergeSimilar(general []int, data []float32, maxDistance int) []int {
+	byScore := slices.Clone(general)
+	slices.SortFunc(byScore, func(a, b int) int {
+		return compareTags(t.names[a], data[a], t.names[b], data[b])
+	})
+
+	var kept [][]rune
+	merged := map[int]struct{}{}
+	for _, index := range byScore {
+		name := []rune(t.names[index])
+		if len(name) >= minSimilarTagLength {
+			similar := slices.ContainsFunc(kept, func(other []rune) bool {
+				return levenshtein(name, other) <= maxDistance
+			})
+			if similar {
+				merged[index] = struct{}{}
+				continue
+			}
+			kept = append(kept, name)
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(general), func(index int) bool {
+		_, ok := merged[index]
+		return ok
+	})
+}
+
+// levenshtein returns the amount of single rune insertions, deletions and substitutions turning a into b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := range a {
+		curr[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			curr[j+1] = min(prev[j+1]+1, curr[j]+1, prev[j]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
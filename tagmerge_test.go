@@ -0,0 +1,38 @@
+package gotagger
+
+import "testing"
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"smile", "", 5},
+		{"", "smile", 5},
+		{"smile", "smile", 0},
+		{"hat", "cat", 1},
+		{"smile", "smiling", 3},
+		{"kitten", "sitting", 3},
+		{"猫耳", "猫の耳", 1},
+	}
+
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q): got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMergeSimilarTags(t *testing.T) {
+	tags := &modelTags{
+		names:          []string{"smile", "smiling", "hat", "cat", "long hair"},
+		generalIndexes: []int{0, 1, 2, 3, 4},
+	}
+	data := []float32{0.6, 0.8, 0.7, 0.5, 0.9}
+
+	p := tags.buildPredictions(data, RunOptions{GeneralThreshold: 0.35, MergeSimilarTags: 3})
+
+	// smile is merged into the higher scoring smiling, hat and cat are too short to be merged
+	assertNames(t, "general", p.General, []string{"smiling", "hat", "cat", "long hair"})
+}
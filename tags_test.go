@@ -0,0 +1,235 @@
+package gotagger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+func TestReadTagsCategories(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{
+			name: "integers",
+			csv: "tag_id,name,category,count\n" +
+				"1,general,9,10\n2,sensitive,9,10\n3,long_hair,0,10\n4,smile,0,10\n5,hatsune_miku,4,10\n",
+		},
+		{
+			name: "floats",
+			csv: "tag_id,name,category,count\n" +
+				"1,general,9.0,10\n2,sensitive,9.0,10\n3,long_hair,0.0,10\n4,smile,0.0,10\n5,hatsune_miku,4.0,10\n",
+		},
+		{
+			name: "quoted",
+			csv: "tag_id,name,category,count\n" +
+				"1,general,\"9\",10\n2,sensitive,\"9\",10\n3,long_hair,\"0\",10\n4,smile,\" 0 \",10\n" +
+				"5,hatsune_miku,\"4\",10\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := readTags(strings.NewReader(tt.csv), tagsFormat{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(tags.ratingIndexes, []int{0, 1}) {
+				t.Errorf("rating indexes: got %v, want [0 1]", tags.ratingIndexes)
+			}
+			if !slices.Equal(tags.generalIndexes, []int{2, 3}) {
+				t.Errorf("general indexes: got %v, want [2 3]", tags.generalIndexes)
+			}
+			if !slices.Equal(tags.characterIndexes, []int{4}) {
+				t.Errorf("character indexes: got %v, want [4]", tags.characterIndexes)
+			}
+			if tags.names[2] != "long hair" || tags.rawNames[2] != "long_hair" {
+				t.Errorf("name: got %q (%q), want \"long hair\" (\"long_hair\")", tags.names[2], tags.rawNames[2])
+			}
+		})
+	}
+}
+
+func TestLoadTagsGzip(t *testing.T) {
+	csv := "tag_id,name,category,count\n1,general,9,10\n2,long_hair,0,10\n3,hatsune_miku,4,10\n"
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(csv))
+	gz.Close()
+
+	path := filepath.Join(t.TempDir(), "selected_tags.csv.gz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := loadTags(path, tagsFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"general", "long hair", "hatsune miku"}; !slices.Equal(tags.names, want) {
+		t.Errorf("names: got %v, want %v", tags.names, want)
+	}
+}
+
+func TestReadTagsDelimiter(t *testing.T) {
+	tests := []struct {
+		name      string
+		delimiter rune
+		data      string
+	}{
+		{"tsv", '\t', "tag_id\tname\tcategory\tcount\n1\tgeneral\t9\t10\n2\tlong_hair\t0\t10\n3\thatsune_miku\t4\t10\n"},
+		{"semicolon", ';', "tag_id;name;category;count\n1;general;9;10\n2;long_hair;0;10\n3;hatsune_miku;4;10\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := readTags(strings.NewReader(tt.data), tagsFormat{delimiter: tt.delimiter})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := []string{"general", "long hair", "hatsune miku"}; !slices.Equal(tags.names, want) {
+				t.Errorf("names: got %v, want %v", tags.names, want)
+			}
+			if !slices.Equal(tags.characterIndexes, []int{2}) {
+				t.Errorf("character indexes: got %v, want [2]", tags.characterIndexes)
+			}
+			if !slices.Equal(tags.counts, []int{10, 10, 10}) {
+				t.Errorf("counts: got %v, want [10 10 10]", tags.counts)
+			}
+		})
+	}
+}
+
+func TestReloadTagsCategories(t *testing.T) {
+	dir := t.TempDir()
+	full := filepath.Join(dir, "full.csv")
+	generalOnly := filepath.Join(dir, "general.csv")
+	os.WriteFile(full, []byte("tag_id,name,category,count\n1,general,9,10\n2,long_hair,0,10\n3,hatsune_miku,4,10\n"), 0o644)
+	os.WriteFile(generalOnly, []byte("tag_id,name,category,count\n1,long_hair,0,10\n2,smile,0,10\n3,hat,0,10\n"), 0o644)
+
+	var logs bytes.Buffer
+	s := TaggerSession{
+		modelTags:        &modelTags{},
+		output:           ort.NewShape(1, 3),
+		logger:           slog.New(slog.NewTextHandler(&logs, nil)),
+		mu:               &sync.Mutex{},
+		strictCategories: true,
+	}
+	if err := s.ReloadTags(full); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ReloadTags(generalOnly); !errors.Is(err, ErrMissingCategory) {
+		t.Fatalf("strict: got %v, want ErrMissingCategory", err)
+	}
+	if s.tagsPath != full {
+		t.Errorf("strict: the tags were replaced by %s", s.tagsPath)
+	}
+
+	s.strictCategories = false
+	if err := s.ReloadTags(generalOnly); err != nil {
+		t.Fatalf("not strict: got %v, want nil", err)
+	}
+	if s.tagsPath != generalOnly || !strings.Contains(logs.String(), "malformed or mismatched") {
+		t.Errorf("not strict: the tags weren't replaced with a warning, logged %q", logs.String())
+	}
+}
+
+func TestLoadTagFiles(t *testing.T) {
+	dir := t.TempDir()
+	general := filepath.Join(dir, "general.csv")
+	other := filepath.Join(dir, "other.csv")
+	os.WriteFile(general, []byte("tag_id,name,category,count\n1,long_hair,0,10\n2,smile,0,10\n"), 0o644)
+	os.WriteFile(other, []byte("tag_id,name,category,count\n1,general,9,10\n2,hatsune_miku,4,10\n"), 0o644)
+
+	tags, err := loadTagFiles([]string{general, other}, tagsFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"long hair", "smile", "general", "hatsune miku"}; !slices.Equal(tags.names, want) {
+		t.Errorf("names: got %v, want %v", tags.names, want)
+	}
+	if !slices.Equal(tags.generalIndexes, []int{0, 1}) ||
+		!slices.Equal(tags.ratingIndexes, []int{2}) ||
+		!slices.Equal(tags.characterIndexes, []int{3}) {
+		t.Errorf(
+			"indexes: got general %v, rating %v and character %v",
+			tags.generalIndexes,
+			tags.ratingIndexes,
+			tags.characterIndexes,
+		)
+	}
+
+	if err := checkTagCount(ort.NewShape(1, 4), len(tags.names)); err != nil {
+		t.Errorf("matching output: got %v, want nil", err)
+	}
+	if err := checkTagCount(ort.NewShape(1, 5), len(tags.names)); err == nil {
+		t.Error("larger output: expected an error")
+	}
+	if err := checkTagCount(ort.NewShape(1, -1), len(tags.names)); err != nil {
+		t.Errorf("dynamic output: got %v, want nil", err)
+	}
+}
+
+func TestCheckIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    modelTags
+		wantErr bool
+	}{
+		{"contiguous", modelTags{names: []string{"a", "b", "c"}, generalIndexes: []int{0, 2}, ratingIndexes: []int{1}}, false},
+		{"gap", modelTags{names: []string{"a", "b", "c"}, generalIndexes: []int{0, 2}}, true},
+		{"overlap", modelTags{names: []string{"a", "b"}, generalIndexes: []int{0, 1}, ratingIndexes: []int{1}}, true},
+		{"out of range", modelTags{names: []string{"a"}, generalIndexes: []int{0, 1}}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.tags.checkIndexes(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReloadTagsCopies(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.csv")
+	second := filepath.Join(dir, "second.csv")
+	os.WriteFile(first, []byte("tag_id,name,category,count\n1,general,9,10\n2,long_hair,0,10\n3,hatsune_miku,4,10\n"), 0o644)
+	os.WriteFile(second, []byte("tag_id,name,category,count\n1,general,9,10\n2,short_hair,0,10\n3,kagamine_rin,4,10\n"), 0o644)
+
+	s := TaggerSession{
+		modelTags: &modelTags{},
+		output:    ort.NewShape(1, 3),
+		logger:    slog.New(slog.DiscardHandler),
+		mu:        &sync.Mutex{},
+	}
+	if err := s.ReloadTags(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// a copy shares the tags, reloading through it replaces them for s too
+	c := s
+	if err := c.ReloadTags(second); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"general", "short hair", "kagamine rin"}; !slices.Equal(s.names, want) {
+		t.Errorf("names of the original: got %v, want %v", s.names, want)
+	}
+	if s.tagsPath != second {
+		t.Errorf("tags path of the original: got %s, want %s", s.tagsPath, second)
+	}
+}
@@ -0,0 +1,61 @@
+package gotagger
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TagSet is a parsed tags dataset.
+//
+// The dataset is a CSV (optionally gzip compressed) with a name and a category column, and optionally a count
+// column with the post count of every tag. Row i is the tag of output i of the model and the category is
+// a Danbooru code: 0 general, 1 artist, 3 copyright, 4 character, 5 meta and 9 rating.
+type TagSet struct {
+	// Names are the tag names in model output order, with underscores replaced by spaces except for kaomojis
+	Names []string
+	// RawNames are the names as found in the dataset
+	RawNames []string
+	// Categories are the categories of every tag
+	Categories []Category
+	// Counts is how many tags each category has, categories without tags are not present
+	Counts map[Category]int
+}
+
+// LoadTags parses and validates the tags dataset at path without creating a session,
+// so it doesn't need the ORT library nor the model.
+//
+// The dataset is read like New reads it, use it to check a tags file early, like in CI or at startup.
+func LoadTags(path string) (TagSet, error) {
+	tags, err := loadTags(path, tagsFormat{})
+	if err != nil {
+		return TagSet{}, err
+	}
+	if len(tags.names) == 0 {
+		return TagSet{}, fmt.Errorf("tags dataset %s has no tags", path)
+	}
+
+	set := TagSet{
+		Names:      slices.Clone(tags.names),
+		RawNames:   slices.Clone(tags.rawNames),
+		Categories: make([]Category, len(tags.names)),
+		Counts:     make(map[Category]int),
+	}
+	for _, category := range []Category{
+		CategoryGeneral,
+		CategoryCharacter,
+		CategoryRating,
+		CategoryArtist,
+		CategoryCopyright,
+		CategoryMeta,
+	} {
+		indexes := tags.indexes(category)
+		for _, index := range indexes {
+			set.Categories[index] = category
+		}
+		if len(indexes) != 0 {
+			set.Counts[category] = len(indexes)
+		}
+	}
+
+	return set, nil
+}
@@ -0,0 +1,37 @@
+package gotagger
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// LoadThresholds reads per tag thresholds for RunOptions.PerTagThresholds from a CSV with the name
+// and threshold columns, names are used as is so they must match the names in Predictions.
+// Gzip compressed files are detected and decompressed.
+func LoadThresholds(path string) (map[string]float32, error) {
+	r, closeFile, err := openDataset(path)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	df := tagsFormat{}.readCSV(r)
+	if !slices.Contains(df.Names(), "name") || !slices.Contains(df.Names(), "threshold") {
+		return nil, fmt.Errorf("thresholds file %s must have the name and threshold columns", path)
+	}
+
+	nameCol := df.Col("name").Records()
+	thresholdCol := df.Col("threshold").Records()
+
+	thresholds := make(map[string]float32, len(nameCol))
+	for i, record := range thresholdCol {
+		threshold, err := strconv.ParseFloat(record, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q of tag %s: %w", record, nameCol[i], err)
+		}
+		thresholds[nameCol[i]] = float32(threshold)
+	}
+
+	return thresholds, nil
+}
@@ -0,0 +1,75 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// RunTiled splits img in a grid of rows x cols tiles, tags every tile and merges them into a single Predictions
+// keeping the highest score of every tag, which helps with dense scenes and very large images.
+//
+// The per tile predictions are returned too, in row-major order.
+func (s *TaggerSession) RunTiled(img image.Image, rows, cols int, opts RunOptions) (Predictions, []Predictions, error) {
+	// only the tiles go through the other checks of validateImage, a whole image too large to tag is fine
+	if err := checkEmpty(img); err != nil {
+		return Predictions{}, nil, &ImageError{Index: 0, Err: err}
+	}
+
+	bounds := img.Bounds()
+	if rows < 1 || cols < 1 {
+		return Predictions{}, nil, errors.New("rows and cols must be at least 1")
+	}
+	if rows > bounds.Dy() || cols > bounds.Dx() {
+		return Predictions{}, nil, errors.New("the image is too small for the tile grid")
+	}
+
+	tiles := make([]image.Image, 0, rows*cols)
+	for r := range rows {
+		for c := range cols {
+			tile := image.Rect(
+				bounds.Min.X+bounds.Dx()*c/cols,
+				bounds.Min.Y+bounds.Dy()*r/rows,
+				bounds.Min.X+bounds.Dx()*(c+1)/cols,
+				bounds.Min.Y+bounds.Dy()*(r+1)/rows,
+			)
+			tiles = append(tiles, imaging.Crop(img, tile))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := s.runRaw(tiles, opts)
+	if err != nil {
+		return Predictions{}, nil, err
+	}
+
+	perTile, err := s.predictionsFromRaw(raw, opts)
+	if err != nil {
+		return Predictions{}, nil, err
+	}
+
+	predictions, err := s.predictionsFromRaw([][]float32{maxPool(raw)}, opts)
+	if err != nil {
+		return Predictions{}, nil, err
+	}
+
+	return predictions[0], perTile, nil
+}
+
+// RunRegion tags only the rect region of img, like a box found by an object detector.
+// rect must be a non empty rectangle inside the bounds of img.
+func (s *TaggerSession) RunRegion(img image.Image, rect image.Rectangle, opts RunOptions) (Predictions, error) {
+	// the region goes through the other checks of validateImage when tagged
+	if err := checkEmpty(img); err != nil {
+		return Predictions{}, &ImageError{Index: 0, Err: err}
+	}
+	if rect.Empty() || !rect.In(img.Bounds()) {
+		return Predictions{}, fmt.Errorf("region %v is empty or outside of the image bounds %v", rect, img.Bounds())
+	}
+
+	return s.RunOne(imaging.Crop(img, rect), opts)
+}
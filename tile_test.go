@@ -0,0 +1,35 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestRunTiledEmptyImage(t *testing.T) {
+	var s TaggerSession
+	for _, img := range []image.Image{nil, image.NewNRGBA(image.Rect(0, 0, 0, 10))} {
+		_, _, err := s.RunTiled(img, 2, 2, RunOptions{})
+
+		var imgErr *ImageError
+		if !errors.As(err, &imgErr) || !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("%v: got %v, want an ImageError matching ErrEmptyImage", img, err)
+		}
+	}
+}
+
+func TestRunRegionInvalid(t *testing.T) {
+	var s TaggerSession
+
+	_, err := s.RunRegion(nil, image.Rect(0, 0, 4, 4), RunOptions{})
+	if !errors.Is(err, ErrEmptyImage) {
+		t.Errorf("nil image: got %v, want ErrEmptyImage", err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for _, rect := range []image.Rectangle{image.Rect(2, 2, 2, 8), image.Rect(5, 5, 15, 15)} {
+		if _, err := s.RunRegion(img, rect, RunOptions{}); err == nil {
+			t.Errorf("region %v: expected an error", rect)
+		}
+	}
+}
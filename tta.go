@@ -0,0 +1,97 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// TTA are the test-time augmentation variants of RunOptions.TTA, they can be combined with |
+type TTA int
+
+const (
+	// TTAHFlip adds the horizontally flipped image
+	TTAHFlip TTA = 1 << iota
+	// TTAVFlip adds the vertically flipped image
+	TTAVFlip
+	// TTACenterCrop adds the centered crop of ttaCropRatio of the image
+	TTACenterCrop
+	// TTACornerCrops adds the four corner crops of ttaCropRatio of the image
+	TTACornerCrops
+)
+
+// ttaCropRatio is the size of the crops of TTACenterCrop and TTACornerCrops relative to the image
+const ttaCropRatio = 0.875
+
+// augment returns img followed by the variants of tta
+func augment(img image.Image, tta TTA) []image.Image {
+	variants := []image.Image{img}
+	if tta&TTAHFlip != 0 {
+		variants = append(variants, imaging.FlipH(img))
+	}
+	if tta&TTAVFlip != 0 {
+		variants = append(variants, imaging.FlipV(img))
+	}
+
+	bounds := img.Bounds()
+	w := max(int(float64(bounds.Dx())*ttaCropRatio), 1)
+	h := max(int(float64(bounds.Dy())*ttaCropRatio), 1)
+	if tta&TTACenterCrop != 0 {
+		variants = append(variants, imaging.CropCenter(img, w, h))
+	}
+	if tta&TTACornerCrops != 0 {
+		for _, anchor := range []imaging.Anchor{
+			imaging.TopLeft,
+			imaging.TopRight,
+			imaging.BottomLeft,
+			imaging.BottomRight,
+		} {
+			variants = append(variants, imaging.CropAnchor(img, w, h, anchor))
+		}
+	}
+
+	return variants
+}
+
+// runAugmented is runRaw with every image replaced by the mean scores of its opts.TTA variants
+func (s *TaggerSession) runAugmented(images []image.Image, opts RunOptions) ([][]float32, error) {
+	var (
+		all      []image.Image
+		variants int
+	)
+	for i, img := range images {
+		if err := validateImage(img, opts); err != nil {
+			return nil, &ImageError{Index: i, Err: err}
+		}
+
+		augmented := augment(img, opts.TTA)
+		variants = len(augmented)
+		all = append(all, augmented...)
+	}
+
+	out, err := s.runImages(all, opts)
+	if err != nil {
+		var imageErr *ImageError
+		if errors.As(err, &imageErr) {
+			imageErr.Index /= variants
+		}
+		return nil, err
+	}
+
+	raw := make([][]float32, len(images))
+	for i := range images {
+		mean := make([]float32, len(out[i*variants]))
+		for _, data := range out[i*variants : (i+1)*variants] {
+			for k, pred := range data {
+				mean[k] += pred
+			}
+		}
+		for k := range mean {
+			mean[k] /= float32(variants)
+		}
+		raw[i] = mean
+	}
+
+	return raw, nil
+}
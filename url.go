@@ -0,0 +1,95 @@
+package gotagger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultFetchTimeout is the RunURL timeout when RunOptions.FetchTimeout is not set
+	defaultFetchTimeout = 30 * time.Second
+	// defaultMaxDownloadSize is the RunURL size limit when RunOptions.MaxDownloadSize is not set
+	defaultMaxDownloadSize = 32 << 20
+)
+
+// ErrDownloadTooLarge is returned by RunURL when the image is larger than RunOptions.MaxDownloadSize
+var ErrDownloadTooLarge = errors.New("download is too large")
+
+// RunURL downloads the image at url, decodes it with DecodeBytes and tags it.
+//
+// The download is canceled with ctx or after RunOptions.FetchTimeout (30 seconds by default), the response must
+// be a 200 with an image content type and at most RunOptions.MaxDownloadSize bytes (32 MiB by default).
+func (s *TaggerSession) RunURL(ctx context.Context, url string, opts RunOptions) (Predictions, error) {
+	body, err := fetchImage(ctx, url, opts)
+	if err != nil {
+		return Predictions{}, err
+	}
+
+	img, err := DecodeBytes(body)
+	if err != nil {
+		return Predictions{}, fmt.Errorf("%s: %w", url, err)
+	}
+
+	return s.RunOne(img, opts)
+}
+
+// fetchImage downloads the body of url, checking its status, content type and size
+func fetchImage(ctx context.Context, url string, opts RunOptions) ([]byte, error) {
+	timeout := opts.FetchTimeout
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
+	}
+	limit := opts.MaxDownloadSize
+	if limit <= 0 {
+		limit = defaultMaxDownloadSize
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", url, err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error while fetching %s: unexpected status %s", url, resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("%s has content type %q, expected an image", url, resp.Header.Get("Content-Type"))
+	}
+
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%s has %d bytes: %w", url, resp.ContentLength, ErrDownloadTooLarge)
+	}
+
+	// one more byte than the limit tells a body of exactly limit bytes from a larger one
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("error while reading %s: %w", url, err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes: %w", url, limit, ErrDownloadTooLarge)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,95 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+)
+
+var (
+	// ErrEmptyImage is returned for images with no pixels, like the result of a truncated download
+	ErrEmptyImage = errors.New("image is empty")
+	// ErrGrayscale is returned for grayscale images when RunOptions.RejectGrayscale is set
+	ErrGrayscale = errors.New("grayscale images are not accepted")
+	// ErrLowResolution is returned for images whose smaller side is below RunOptions.MinResolution
+	ErrLowResolution = errors.New("image resolution is too low")
+	// ErrNonFinite is returned when the model outputs NaN or Inf and RunOptions.ZeroNonFinite is not set
+	ErrNonFinite = errors.New("model output is not finite")
+	// ErrImageTooLarge is returned for images whose larger side is above MaxCanvasDimension
+	ErrImageTooLarge = errors.New("image is too large")
+)
+
+// MaxCanvasDimension is the largest side of an image accepted for preprocessing, padding a larger image
+// to a square could allocate gigabytes. Images downscaled by RunOptions.MaxInputDimension to at most this size
+// are accepted whatever their size, 0 disables the check
+var MaxCanvasDimension = 16384
+
+// Validate runs the checks done before inference on every image without running the model,
+// errs[i] is non-nil when images[i] would be rejected with opts
+func Validate(images []image.Image, opts RunOptions) (errs []error) {
+	errs = make([]error, len(images))
+	for i, img := range images {
+		if err := validateImage(img, opts); err != nil {
+			errs[i] = &ImageError{Index: i, Err: err}
+		}
+	}
+
+	return errs
+}
+
+// validateImage checks that img can be tagged with opts
+func validateImage(img image.Image, opts RunOptions) error {
+	if err := checkEmpty(img); err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	limited := opts.MaxInputDimension > 0 && opts.MaxInputDimension <= MaxCanvasDimension
+	if side := max(bounds.Dx(), bounds.Dy()); MaxCanvasDimension > 0 && side > MaxCanvasDimension && !limited {
+		return fmt.Errorf("%w: %dx%d is above %d", ErrImageTooLarge, bounds.Dx(), bounds.Dy(), MaxCanvasDimension)
+	}
+
+	if side := min(bounds.Dx(), bounds.Dy()); side < opts.MinResolution {
+		return fmt.Errorf("%w: %dx%d is below %d", ErrLowResolution, bounds.Dx(), bounds.Dy(), opts.MinResolution)
+	}
+
+	if opts.RejectGrayscale {
+		switch img.(type) {
+		case *image.Gray, *image.Gray16:
+			return ErrGrayscale
+		}
+	}
+
+	return nil
+}
+
+// checkEmpty returns ErrEmptyImage when img is nil or has no pixels
+func checkEmpty(img image.Image) error {
+	if img == nil {
+		return ErrEmptyImage
+	}
+
+	if bounds := img.Bounds(); bounds.Dx() <= 0 || bounds.Dy() <= 0 {
+		return fmt.Errorf("%w: bounds are %v", ErrEmptyImage, bounds)
+	}
+
+	return nil
+}
+
+// checkFinite returns ErrNonFinite when data has NaN or Inf values, or replaces them with 0 if zero is set
+func checkFinite(data []float32, zero bool) error {
+	for i, pred := range data {
+		f := float64(pred)
+		if !math.IsNaN(f) && !math.IsInf(f, 0) {
+			continue
+		}
+
+		if !zero {
+			return fmt.Errorf("%w: index %d is %v", ErrNonFinite, i, pred)
+		}
+		data[i] = 0
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package gotagger
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestValidateMinResolution(t *testing.T) {
+	images := []image.Image{
+		image.NewNRGBA(image.Rect(0, 0, 32, 32)),
+		image.NewNRGBA(image.Rect(0, 0, 64, 64)),
+		image.NewNRGBA(image.Rect(0, 0, 128, 48)),
+	}
+
+	errs := Validate(images, RunOptions{MinResolution: 64})
+
+	var imgErr *ImageError
+	if !errors.As(errs[0], &imgErr) || imgErr.Index != 0 {
+		t.Fatalf("32x32: got %v, want an ImageError for image 0", errs[0])
+	}
+	if !errors.Is(errs[0], ErrLowResolution) {
+		t.Errorf("32x32: got %v, want ErrLowResolution", errs[0])
+	}
+	if errs[1] != nil {
+		t.Errorf("64x64: got %v, want nil", errs[1])
+	}
+	if !errors.Is(errs[2], ErrLowResolution) {
+		t.Errorf("128x48: got %v, want ErrLowResolution", errs[2])
+	}
+}
+
+func TestValidateGrayscale(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 8, 8))
+	gray16 := image.NewGray16(image.Rect(0, 0, 8, 8))
+
+	for _, img := range []image.Image{gray, gray16} {
+		if err := validateImage(img, RunOptions{RejectGrayscale: true}); !errors.Is(err, ErrGrayscale) {
+			t.Errorf("%T with RejectGrayscale: got %v, want ErrGrayscale", img, err)
+		}
+		if err := validateImage(img, RunOptions{}); err != nil {
+			t.Errorf("%T: got %v, want nil", img, err)
+		}
+	}
+}
+
+func TestCheckFinite(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+
+	if err := checkFinite([]float32{0.1, 0.9}, false); err != nil {
+		t.Errorf("finite output: got %v, want nil", err)
+	}
+	for _, data := range [][]float32{{0.1, nan}, {inf, 0.2}, {0.3, -inf}} {
+		if err := checkFinite(data, false); !errors.Is(err, ErrNonFinite) {
+			t.Errorf("%v: got %v, want ErrNonFinite", data, err)
+		}
+	}
+
+	data := []float32{nan, 0.5, inf, -inf}
+	if err := checkFinite(data, true); err != nil {
+		t.Fatalf("zeroing: got %v, want nil", err)
+	}
+	if want := []float32{0, 0.5, 0, 0}; !slices.Equal(data, want) {
+		t.Errorf("zeroing: got %v, want %v", data, want)
+	}
+}
+
+func TestValidateEmptyImage(t *testing.T) {
+	for _, img := range []image.Image{
+		nil,
+		image.NewNRGBA(image.Rect(0, 0, 0, 0)),
+		image.NewNRGBA(image.Rect(0, 0, 16, 0)),
+		image.NewNRGBA(image.Rect(5, 5, 5, 10)),
+	} {
+		if err := validateImage(img, RunOptions{}); !errors.Is(err, ErrEmptyImage) {
+			t.Errorf("%v: got %v, want ErrEmptyImage", img, err)
+		}
+	}
+}
+
+// boundsImage is an image of any size without pixels, for the checks that only look at the bounds
+type boundsImage struct {
+	image.Rectangle
+}
+
+func (i boundsImage) ColorModel() color.Model { return color.NRGBAModel }
+func (i boundsImage) Bounds() image.Rectangle { return i.Rectangle }
+func (i boundsImage) At(x, y int) color.Color { return color.NRGBA{} }
+
+func TestValidateMaxCanvasDimension(t *testing.T) {
+	huge := boundsImage{image.Rect(0, 0, 100_000, 50)}
+	edge := boundsImage{image.Rect(0, 0, MaxCanvasDimension, 50)}
+
+	if err := validateImage(huge, RunOptions{}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("huge image: got %v, want ErrImageTooLarge", err)
+	}
+	if err := validateImage(edge, RunOptions{}); err != nil {
+		t.Errorf("image at the limit: got %v, want nil", err)
+	}
+	// MaxInputDimension downscales it before any canvas is allocated
+	if err := validateImage(huge, RunOptions{MaxInputDimension: 4096}); err != nil {
+		t.Errorf("huge image with MaxInputDimension: got %v, want nil", err)
+	}
+	if err := validateImage(huge, RunOptions{MaxInputDimension: MaxCanvasDimension + 1}); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("huge image with MaxInputDimension above the limit: got %v, want ErrImageTooLarge", err)
+	}
+
+	defer func(limit int) { MaxCanvasDimension = limit }(MaxCanvasDimension)
+	MaxCanvasDimension = 0
+	if err := validateImage(huge, RunOptions{}); err != nil {
+		t.Errorf("huge image without limit: got %v, want nil", err)
+	}
+}
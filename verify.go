@@ -0,0 +1,114 @@
+package gotagger
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+
+	"github.com/disintegration/imaging"
+)
+
+// ErrVerify is matched by the errors of Verify
+var ErrVerify = errors.New("model and tags don't look like a match")
+
+// Verify checks that the model and the tags dataset look like they belong together, catching the common
+// mistake of pairing a model with the tags of another one.
+//
+// The tags must have as many entries as the model outputs and some ratings, and tagging a black image
+// must give finite scores with ratings between 0 and 1 summing to about 1. It can't catch two datasets
+// of the same size and layout, the first tags are logged at debug level to compare them by eye.
+func (s *TaggerSession) Verify() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logger.Debug("verifying tags", "first", s.names[:min(5, len(s.names))], "ratings", len(s.ratingIndexes))
+
+	if err := checkTagCount(s.output, len(s.names)); err != nil {
+		return fmt.Errorf("%w: %w", ErrVerify, err)
+	}
+	if len(s.ratingIndexes) == 0 {
+		return fmt.Errorf("%w: the tags dataset has no ratings", ErrVerify)
+	}
+
+	black := imaging.New(s.targetSize, s.targetSize, color.Black)
+	raw, err := s.runRaw([]image.Image{black}, RunOptions{})
+	if err != nil {
+		return err
+	}
+
+	data := raw[0]
+	if err := checkFinite(data, false); err != nil {
+		return fmt.Errorf("%w: %w", ErrVerify, err)
+	}
+
+	if err := s.checkRatings(data); err != nil {
+		return fmt.Errorf("%w: %w", ErrVerify, err)
+	}
+
+	return nil
+}
+
+// ratingSumTolerance is how far from 1 the sum of the rating scores can be in Verify,
+// the ratings are independent sigmoids so they only roughly sum 1
+const ratingSumTolerance = 0.5
+
+// checkRatings returns an error when the rating scores of data are not probabilities summing to about 1
+func (t *modelTags) checkRatings(data []float32) error {
+	var sum float32
+	for _, index := range t.ratingIndexes {
+		if index >= len(data) {
+			return fmt.Errorf("rating %s is past the model output", t.names[index])
+		}
+
+		score := data[index]
+		if score < 0 || score > 1 {
+			return fmt.Errorf(
+				"rating %s scored %v, the outputs are not probabilities or not aligned with the tags",
+				t.names[index],
+				score,
+			)
+		}
+		sum += score
+	}
+	if sum < 1-ratingSumTolerance || sum > 1+ratingSumTolerance {
+		return fmt.Errorf("the ratings sum %v, the outputs are not aligned with the tags", sum)
+	}
+
+	return nil
+}
+
+// ratingNames are the rating labels of the WD tags datasets
+var ratingNames = []string{"general", "sensitive", "questionable", "explicit"}
+
+// unknownRatings returns the names of the rating tags that are not one of ratingNames,
+// which points to a dataset with shifted categories
+func (t *modelTags) unknownRatings() []string {
+	var unknown []string
+	for _, index := range t.ratingIndexes {
+		if !slices.Contains(ratingNames, t.rawNames[index]) {
+			unknown = append(unknown, t.rawNames[index])
+		}
+	}
+
+	return unknown
+}
+
+// Ping runs a black image through the session and returns nil if it succeeds, as a readiness check.
+//
+// The black image is preprocessed once and reused, so it only costs a single image inference.
+func (s *TaggerSession) Ping() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pingInput == nil {
+		black := imaging.New(s.targetSize, s.targetSize, color.Black)
+		s.pingInput = prepareInput(black, s.targetSize, s.channelOrder, RunOptions{})
+	}
+
+	_, err := s.runBatches(1, RunOptions{}, func(int) ([]float32, error) {
+		return s.pingInput, nil
+	})
+	return err
+}
@@ -0,0 +1,50 @@
+package gotagger
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckRatings(t *testing.T) {
+	tags := testTags()
+	rest := []float32{0.9, 0.6, 0.4, 0.3, 0.1, 0.95, 0.5, 0.2}
+
+	tests := []struct {
+		name    string
+		ratings []float32
+		wantErr bool
+	}{
+		{"probabilities", []float32{0.7, 0.2, 0.05, 0.01}, false},
+		{"within tolerance", []float32{0.9, 0.4, 0.1, 0.05}, false},
+		{"all zero", []float32{0, 0, 0, 0}, true},
+		{"too low", []float32{0.2, 0.1, 0.05, 0.01}, true},
+		{"too high", []float32{0.9, 0.8, 0.7, 0.6}, true},
+		{"logits", []float32{2.5, -1, -3, -4}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tags.checkRatings(append(slices.Clone(tt.ratings), rest...))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := tags.checkRatings([]float32{0.7, 0.2}); err == nil {
+		t.Error("short output: expected an error")
+	}
+}
+
+func TestUnknownRatings(t *testing.T) {
+	tags := testTags()
+	if unknown := tags.unknownRatings(); unknown != nil {
+		t.Errorf("WD ratings: got %v, want none", unknown)
+	}
+
+	// a dataset whose ratings are shifted by one tag
+	tags.ratingIndexes = []int{1, 2, 3, 4}
+	if unknown := tags.unknownRatings(); !slices.Equal(unknown, []string{"long hair"}) {
+		t.Errorf("shifted ratings: got %v, want [long hair]", unknown)
+	}
+}